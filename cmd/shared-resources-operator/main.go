@@ -23,7 +23,9 @@ import (
 )
 
 var (
-	kubeconfig string
+	kubeconfig      string
+	guestKubeconfig string
+	leaderElection  = operator.NewLeaderElectionOptions()
 )
 
 func main() {
@@ -57,10 +59,13 @@ func NewOperatorCommand() *cobra.Command {
 		version.Get(),
 		runOperatorWithKubeconfig,
 	)
+	// leader election is done by the operator itself, so that non-leaders keep serving and report not ready
 	ctrlCmdConfig.DisableLeaderElection = true
 	ctrlCmd := ctrlCmdConfig.NewCommandWithContext(context.TODO()) //TODO cmd.Context()) came back with panic: cannot create context from nil parent
 	ctrlCmd.Use = "start"
 	ctrlCmd.Short = "Start the Projected Shared Resources Operator"
+	leaderElection.AddFlags(ctrlCmd.Flags())
+	ctrlCmd.Flags().StringVar(&guestKubeconfig, "guest-kubeconfig", "", "Path to the kubeconfig of the guest cluster of a hosted control plane. When set, the operator runs in the management cluster, deploys the webhook in its own namespace and manages everything else in the guest cluster.")
 	var err error
 	kubeconfig, err = ctrlCmd.Flags().GetString("kubeconfig")
 	if err != nil {
@@ -70,6 +75,7 @@ func NewOperatorCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(ctrlCmd)
+	cmd.AddCommand(NewValidateConfigCommand())
 
 	return cmd
 }
@@ -88,5 +94,5 @@ func runOperatorWithKubeconfig(ctx context.Context, controllerConfig *controller
 			return err
 		}
 	}
-	return operator.RunOperator(ctx, controllerConfig, kubeconfig)
+	return operator.RunWithLeaderElection(ctx, controllerConfig, kubeconfig, guestKubeconfig, leaderElection)
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+type validateConfigOptions struct {
+	file           string
+	configMap      string
+	namespace      string
+	kubeconfig     string
+	operatorConfig bool
+}
+
+// NewValidateConfigCommand returns the command that validates a driver config.yaml, or with --operator-config
+// the operator configuration, read either from a file or from a live ConfigMap.
+func NewValidateConfigCommand() *cobra.Command {
+	o := &validateConfigOptions{}
+	cmd := &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validate a Projected Shared Resources driver or operator configuration",
+		Long: `Validate a Projected Shared Resources configuration before rolling it out.
+
+The configuration is read from --file, which may hold either the bare config.yaml or a ConfigMap manifest
+embedding it, or from the live ConfigMap named by --configmap. On success the effective configuration,
+with defaults applied, is printed.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(cmd.Context())
+		},
+	}
+	cmd.Flags().StringVarP(&o.file, "file", "f", "", "Path to a config.yaml, or to a ConfigMap manifest embedding one.")
+	cmd.Flags().StringVar(&o.configMap, "configmap", "", fmt.Sprintf("Name of a live ConfigMap to validate, e.g. %s.", config.DriverConfigMapName))
+	cmd.Flags().StringVarP(&o.namespace, "namespace", "n", config.DefaultNamespace, "Namespace of the ConfigMap given with --configmap.")
+	cmd.Flags().StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig used with --configmap; defaults to the standard loading rules.")
+	cmd.Flags().BoolVar(&o.operatorConfig, "operator-config", false, "Validate the operator configuration format instead of the driver config.yaml.")
+	return cmd
+}
+
+func (o *validateConfigOptions) run(ctx context.Context) error {
+	if (len(o.file) == 0) == (len(o.configMap) == 0) {
+		return fmt.Errorf("exactly one of --file or --configmap must be given")
+	}
+
+	var data []byte
+	var err error
+	if len(o.file) > 0 {
+		data, err = o.readFile()
+	} else {
+		data, err = o.readConfigMap(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	var effective interface{}
+	if o.operatorConfig {
+		effective, err = config.ParseOperatorConfig(data)
+		if err != nil {
+			return err
+		}
+	} else {
+		cfg, err := config.ParseConfig(data)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Validate(nil).ToAggregate(); err != nil {
+			return fmt.Errorf("invalid driver configuration: %s", err)
+		}
+		effective = cfg
+	}
+
+	out, err := yaml.Marshal(effective)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "configuration is valid\n---\n%s", out)
+	return nil
+}
+
+// readFile returns the configuration held in o.file, unwrapping it when the file is a ConfigMap manifest.
+func (o *validateConfigOptions) readFile() ([]byte, error) {
+	data, err := os.ReadFile(o.file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q: %s", o.file, err)
+	}
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil || typeMeta.Kind != "ConfigMap" {
+		return data, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := yaml.Unmarshal(data, cm); err != nil {
+		return nil, fmt.Errorf("error unmarshalling ConfigMap in %q: %s", o.file, err)
+	}
+	return configFromConfigMap(cm)
+}
+
+func (o *validateConfigOptions) readConfigMap(ctx context.Context) ([]byte, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubeclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := kubeClient.CoreV1().ConfigMaps(o.namespace).Get(ctx, o.configMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting ConfigMap %s/%s: %s", o.namespace, o.configMap, err)
+	}
+	return configFromConfigMap(cm)
+}
+
+func configFromConfigMap(cm *corev1.ConfigMap) ([]byte, error) {
+	data, ok := cm.Data[config.ConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %q key", cm.Namespace, cm.Name, config.ConfigKey)
+	}
+	return []byte(data), nil
+}
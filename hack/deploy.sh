@@ -10,6 +10,7 @@ set -o pipefail
 # - OPERATOR_IMAGE: the image for the operator to deploy
 # - DRIVER_IMAGE: the image for the CSI driver
 # - NODE_REGISTRAR_IMAGE: the image for the csi node registrar
+# - LIVENESS_PROBE_IMAGE: the image for the csi liveness probe
 # - LOG_LEVEL: log level for the operator
 
 rm -rf _deploy
@@ -19,17 +20,20 @@ cp -r ../cluster-storage-operator/assets/csidriveroperators/shared-resource/* _d
 operatorImage=${OPERATOR_IMAGE:-quay.io/openshift/origin-csi-driver-shared-resource-operator:latest}
 driverImage=${DRIVER_IMAGE:-quay.io/openshift/origin-csi-driver-shared-resource:latest}
 nodeRegistrar=${NODE_REGISTRAR_IMAGE:-quay.io/openshift/origin-csi-node-driver-registrar:latest}
+livenessProbe=${LIVENESS_PROBE_IMAGE:-quay.io/openshift/origin-csi-livenessprobe:latest}
 webhookImage=${WEBHOOK_IMAGE:-quay.io/openshift/origin-csi-driver-shared-resource-webhook:latest}
 logLevel=${LOG_LEVEL:-5}
 
 echo "Deploying operator image ${operatorImage}"
 echo "Deploying driver image ${driverImage}"
 echo "Deploying node registrar image ${nodeRegistrar}"
+echo "Deploying liveness probe image ${livenessProbe}"
 echo "Deploying webhook image ${webhookImage}"
 echo "Using log level ${logLevel}"
 sed -i -e "s|\${OPERATOR_IMAGE}|${operatorImage}|g" \
   -e "s|\${DRIVER_IMAGE}|${driverImage}|g" \
   -e "s|\${NODE_DRIVER_REGISTRAR_IMAGE}|${nodeRegistrar}|g" \
+  -e "s|\${LIVENESS_PROBE_IMAGE}|${livenessProbe}|g" \
   -e "s|\${WEBHOOK_IMAGE}|${webhookImage}|g" \
   -e "s|\${LOG_LEVEL}|${logLevel}|g" \
   _deploy/09_deployment.yaml
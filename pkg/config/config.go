@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// DefaultNamespace is the namespace of the operator and the driver, and of the webhook outside of hosted control
+	// planes.
+	DefaultNamespace = "openshift-cluster-csi-drivers"
+
+	// DriverConfigMapName is the ConfigMap mounted by the driver DaemonSet at /var/run/configmaps/config.
+	DriverConfigMapName = "csi-driver-shared-resource-config"
+	// ConfigKey is the data key holding the YAML configuration, both for the driver and for the operator.
+	ConfigKey = "config.yaml"
+
+	// DefaultShareRelistInterval is the relist interval the driver uses when none is configured.
+	DefaultShareRelistInterval = 10 * time.Minute
+)
+
+// Config mirrors the config.yaml consumed by the Shared Resource CSI Driver. Fields left unset are not
+// rendered, so whatever is already present in the driver ConfigMap is kept for them.
+type Config struct {
+	// IgnoredNamespaces namespace names ignored by the driver.
+	IgnoredNamespaces []string `json:"ignoredNamespaces,omitempty"`
+	// RefreshResources toggles actively watching for resources; when disabled the driver only reads
+	// resources before mount.
+	RefreshResources *bool `json:"refreshResources,omitempty"`
+	// ShareRelistInterval interval to relist all "Share" object instances, e.g. "10m".
+	ShareRelistInterval string `json:"shareRelistInterval,omitempty"`
+}
+
+// NewConfig returns a configuration holding the driver defaults.
+func NewConfig() *Config {
+	refresh := true
+	return &Config{
+		RefreshResources:    &refresh,
+		ShareRelistInterval: DefaultShareRelistInterval.String(),
+	}
+}
+
+// ParseConfig parses a driver config.yaml, rejecting keys the driver does not know about, and fills in the
+// driver defaults for every field left unset. The result is not validated.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing driver configuration: %s", err)
+	}
+	cfg.SetDefaults()
+	return cfg, nil
+}
+
+// SetDefaults fills in the driver defaults for every field left unset.
+func (c *Config) SetDefaults() {
+	defaults := NewConfig()
+	if c.RefreshResources == nil {
+		c.RefreshResources = defaults.RefreshResources
+	}
+	if len(c.ShareRelistInterval) == 0 {
+		c.ShareRelistInterval = defaults.ShareRelistInterval
+	}
+}
+
+// GetShareRelistInterval returns the parsed relist interval, or DefaultShareRelistInterval when it is
+// unset or cannot be parsed.
+func (c *Config) GetShareRelistInterval() time.Duration {
+	d, err := time.ParseDuration(c.ShareRelistInterval)
+	if err != nil || d <= 0 {
+		return DefaultShareRelistInterval
+	}
+	return d
+}
+
+// Validate returns the invalid fields of the configuration, rooted at fldPath, which may be nil.
+func (c *Config) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, ns := range c.IgnoredNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(fldPath.Child("ignoredNamespaces").Index(i), ns, msg))
+		}
+	}
+	if len(c.ShareRelistInterval) > 0 {
+		d, err := time.ParseDuration(c.ShareRelistInterval)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("shareRelistInterval"), c.ShareRelistInterval, err.Error()))
+		} else if d <= 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("shareRelistInterval"), c.ShareRelistInterval, "must be a positive duration"))
+		}
+	}
+	return errs
+}
+
+// MergeInto overlays the fields set in c onto the driver config.yaml in existing, keeping every other key
+// untouched. The original bytes are returned when the overlay does not change anything, so that comments and
+// formatting in a hand-written config.yaml survive until there is an actual change to make.
+func (c *Config) MergeInto(existing []byte) ([]byte, error) {
+	current := map[string]interface{}{}
+	if err := yaml.Unmarshal(existing, &current); err != nil {
+		return nil, fmt.Errorf("error parsing existing driver configuration: %s", err)
+	}
+	if current == nil {
+		current = map[string]interface{}{}
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	managed := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &managed); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]interface{}, len(current)+len(managed))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range managed {
+		merged[k] = v
+	}
+	if equality.Semantic.DeepEqual(current, merged) {
+		return existing, nil
+	}
+	return yaml.Marshal(merged)
+}
+
+// UnmarshalStrict is yaml.Unmarshal that fails on fields obj does not declare.
+func UnmarshalStrict(data []byte, obj interface{}) error {
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(obj)
+}
@@ -0,0 +1,402 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{
+			name: "valid configuration",
+			config: Config{
+				IgnoredNamespaces:   []string{"openshift-machine-api", "kube-system"},
+				ShareRelistInterval: "10m",
+			},
+		},
+		{
+			name: "invalid namespace name",
+			config: Config{
+				IgnoredNamespaces: []string{"openshift-machine-api", "Not_A_Namespace"},
+			},
+			expected: []string{"driverConfig.ignoredNamespaces[1]"},
+		},
+		{
+			name: "unparsable relist interval",
+			config: Config{
+				ShareRelistInterval: "ten minutes",
+			},
+			expected: []string{"driverConfig.shareRelistInterval"},
+		},
+		{
+			name: "negative relist interval",
+			config: Config{
+				ShareRelistInterval: "-1m",
+			},
+			expected: []string{"driverConfig.shareRelistInterval", "must be a positive duration"},
+		},
+	} {
+		errs := test.config.Validate(field.NewPath("driverConfig"))
+		if len(test.expected) == 0 {
+			if len(errs) > 0 {
+				t.Errorf("testcase %s: unexpected errors %v", test.name, errs)
+			}
+			continue
+		}
+		aggregate := errs.ToAggregate()
+		if aggregate == nil {
+			t.Errorf("testcase %s: expected errors containing %v", test.name, test.expected)
+			continue
+		}
+		for _, s := range test.expected {
+			if !strings.Contains(aggregate.Error(), s) {
+				t.Errorf("testcase %s: expected string %s did not appear in %s", test.name, s, aggregate.Error())
+			}
+		}
+	}
+}
+
+func TestMergeInto(t *testing.T) {
+	refresh := false
+	existing := `---
+ignoredNamespaces:
+  - openshift-machine-api
+
+# kept as is
+refreshResources: true
+
+shareRelistInterval: 10m
+someFutureSetting: keep-me
+`
+	for _, test := range []struct {
+		name      string
+		config    Config
+		unchanged bool
+		expected  map[string]interface{}
+	}{
+		{
+			name:      "nothing set keeps the original bytes",
+			config:    Config{},
+			unchanged: true,
+		},
+		{
+			name:      "setting the current value keeps the original bytes",
+			config:    Config{ShareRelistInterval: "10m"},
+			unchanged: true,
+		},
+		{
+			name: "managed fields are overlaid and unmanaged ones preserved",
+			config: Config{
+				IgnoredNamespaces:   []string{"openshift-etcd"},
+				RefreshResources:    &refresh,
+				ShareRelistInterval: "1h",
+			},
+			expected: map[string]interface{}{
+				"ignoredNamespaces":   []interface{}{"openshift-etcd"},
+				"refreshResources":    false,
+				"shareRelistInterval": "1h",
+				"someFutureSetting":   "keep-me",
+			},
+		},
+	} {
+		data, err := test.config.MergeInto([]byte(existing))
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		if test.unchanged {
+			if string(data) != existing {
+				t.Errorf("testcase %s: expected the original configuration, got %s", test.name, string(data))
+			}
+			continue
+		}
+		merged := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &merged); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		for k, v := range test.expected {
+			got, err := yaml.Marshal(merged[k])
+			if err != nil {
+				t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+			}
+			want, _ := yaml.Marshal(v)
+			if string(got) != string(want) {
+				t.Errorf("testcase %s: expected %s to be %s, got %s", test.name, k, want, got)
+			}
+		}
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		data             string
+		expectErr        string
+		expectRefresh    bool
+		expectRelist     string
+		expectNamespaces int
+	}{
+		{
+			name:          "empty configuration is defaulted",
+			data:          "",
+			expectRefresh: true,
+			expectRelist:  "10m0s",
+		},
+		{
+			name: "explicit values are kept",
+			data: `---
+ignoredNamespaces:
+  - openshift-machine-api
+refreshResources: false
+shareRelistInterval: 1h
+`,
+			expectRefresh:    false,
+			expectRelist:     "1h",
+			expectNamespaces: 1,
+		},
+		{
+			name:      "unknown keys are rejected",
+			data:      "refreshResource: true\n",
+			expectErr: `unknown field "refreshResource"`,
+		},
+	} {
+		cfg, err := ParseConfig([]byte(test.data))
+		if len(test.expectErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("testcase %s: expected error containing %q, got %v", test.name, test.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		if *cfg.RefreshResources != test.expectRefresh {
+			t.Errorf("testcase %s: expected refreshResources %v, got %v", test.name, test.expectRefresh, *cfg.RefreshResources)
+		}
+		if cfg.ShareRelistInterval != test.expectRelist {
+			t.Errorf("testcase %s: expected shareRelistInterval %s, got %s", test.name, test.expectRelist, cfg.ShareRelistInterval)
+		}
+		if len(cfg.IgnoredNamespaces) != test.expectNamespaces {
+			t.Errorf("testcase %s: expected %d ignored namespaces, got %v", test.name, test.expectNamespaces, cfg.IgnoredNamespaces)
+		}
+	}
+}
+
+func TestReservedNames(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		config        OperatorConfig
+		expectErrs    []string
+		expectSecrets string
+		expectCMs     string
+	}{
+		{
+			name:          "defaults only",
+			expectSecrets: "openshift-etc-pki-entitlement: openshift-config-managed:etc-pki-entitlement",
+		},
+		{
+			name: "configured names are added to the defaults",
+			config: OperatorConfig{
+				ReservedSharedSecretNames:    map[string]string{"cluster-pull-secret": "openshift-config:pull-secret"},
+				ReservedSharedConfigMapNames: map[string]string{"trusted-ca": "openshift-config-managed:trusted-ca-bundle", "a-share": "ns:cm"},
+			},
+			expectSecrets: "cluster-pull-secret: openshift-config:pull-secret;openshift-etc-pki-entitlement: openshift-config-managed:etc-pki-entitlement",
+			expectCMs:     "a-share: ns:cm;trusted-ca: openshift-config-managed:trusted-ca-bundle",
+		},
+		{
+			name: "invalid syntax",
+			config: OperatorConfig{
+				ReservedSharedSecretNames:    map[string]string{"no-namespace": "pull-secret", "Bad_Share": "ns:name"},
+				ReservedSharedConfigMapNames: map[string]string{"bad-namespace": "Not_A_Namespace:cm"},
+			},
+			expectErrs: []string{
+				"reservedSharedSecretNames[no-namespace]", "must be in the form namespace:name",
+				"reservedSharedSecretNames[Bad_Share]",
+				"reservedSharedConfigMapNames[bad-namespace]", "namespace a lowercase RFC 1123 label",
+			},
+		},
+		{
+			name: "default reservations cannot be redirected",
+			config: OperatorConfig{
+				ReservedSharedSecretNames: map[string]string{"openshift-etc-pki-entitlement": "my-namespace:my-secret"},
+			},
+			expectErrs: []string{"is reserved by the operator for openshift-config-managed:etc-pki-entitlement"},
+		},
+	} {
+		errs := test.config.Validate().ToAggregate()
+		if len(test.expectErrs) > 0 {
+			if errs == nil {
+				t.Errorf("testcase %s: expected errors containing %v", test.name, test.expectErrs)
+				continue
+			}
+			for _, s := range test.expectErrs {
+				if !strings.Contains(errs.Error(), s) {
+					t.Errorf("testcase %s: expected string %s did not appear in %s", test.name, s, errs.Error())
+				}
+			}
+			continue
+		}
+		if errs != nil {
+			t.Errorf("testcase %s: unexpected errors %v", test.name, errs)
+			continue
+		}
+		if secrets := FormatReservedNames(test.config.GetReservedSharedSecretNames()); secrets != test.expectSecrets {
+			t.Errorf("testcase %s: expected reserved SharedSecret names %q, got %q", test.name, test.expectSecrets, secrets)
+		}
+		if cms := FormatReservedNames(test.config.GetReservedSharedConfigMapNames()); cms != test.expectCMs {
+			t.Errorf("testcase %s: expected reserved SharedConfigMap names %q, got %q", test.name, test.expectCMs, cms)
+		}
+	}
+}
+
+func TestNodePlacement(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		data       string
+		expectErrs []string
+	}{
+		{
+			name: "valid placement",
+			data: `nodePlacement:
+  nodeSelector:
+    node-role.kubernetes.io/worker: ""
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+    effect: NoSchedule
+  affinity:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+        - matchExpressions:
+          - key: nvidia.com/gpu.present
+            operator: DoesNotExist
+`,
+		},
+		{
+			name: "invalid placement",
+			data: `nodePlacement:
+  nodeSelector:
+    Not A Label: ""
+  tolerations:
+  - operator: Equal
+    value: "true"
+  - key: dedicated
+    operator: Exists
+    value: edge
+    effect: NoRun
+  affinity:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+        - matchExpressions:
+          - key: zone
+            operator: In
+`,
+			expectErrs: []string{
+				"nodePlacement.nodeSelector",
+				"nodePlacement.tolerations[0].operator", "must be Exists when key is empty",
+				"nodePlacement.tolerations[1].value", "nodePlacement.tolerations[1].effect",
+				"nodePlacement.affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0].values",
+			},
+		},
+	} {
+		_, err := ParseOperatorConfig([]byte(test.data))
+		if len(test.expectErrs) == 0 {
+			if err != nil {
+				t.Errorf("testcase %s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("testcase %s: expected errors containing %v", test.name, test.expectErrs)
+			continue
+		}
+		for _, s := range test.expectErrs {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("testcase %s: expected string %s did not appear in %s", test.name, s, err.Error())
+			}
+		}
+	}
+}
+
+func TestShareGrants(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		data       string
+		expectErrs []string
+	}{
+		{
+			name: "valid grants",
+			data: `shareGrants:
+- sharedSecret: my-share
+  namespaces: [team-a]
+  serviceAccounts: [builder, default]
+- sharedConfigMap: my-share
+  namespaceSelector:
+    matchLabels:
+      team: builds
+  serviceAccounts: [builder]
+`,
+		},
+		{
+			name: "invalid grants",
+			data: `shareGrants:
+- serviceAccounts: [builder]
+- sharedSecret: a
+  sharedConfigMap: b
+  namespaces: [team-a]
+- sharedSecret: my-share
+  namespaceSelector:
+    matchExpressions:
+    - key: team
+      operator: Sometimes
+- sharedSecret: my-share
+  namespaces: [Team-A]
+  serviceAccounts: [builder]
+`,
+			expectErrs: []string{
+				"shareGrants[0]: Required value: sharedSecret or sharedConfigMap must be set",
+				"shareGrants[0].namespaces: Required value: namespaces or namespaceSelector must be set",
+				"shareGrants[1]: Invalid value", "only one of sharedSecret and sharedConfigMap may be set",
+				"shareGrants[1].serviceAccounts: Required value",
+				"shareGrants[2].namespaceSelector",
+				"shareGrants[3].namespaces[0]",
+				`shareGrants[3]: Duplicate value: "sharedsecrets/my-share"`,
+			},
+		},
+		{
+			name: "unknown field",
+			data: `shareGrants:
+- sharedSecret: my-share
+  namespace: [team-a]
+  serviceAccounts: [builder]
+`,
+			expectErrs: []string{`unknown field "namespace"`},
+		},
+	} {
+		_, err := ParseOperatorConfig([]byte(test.data))
+		if len(test.expectErrs) == 0 {
+			if err != nil {
+				t.Errorf("testcase %s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("testcase %s: expected errors containing %v", test.name, test.expectErrs)
+			continue
+		}
+		for _, s := range test.expectErrs {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("testcase %s: expected string %s did not appear in %s", test.name, s, err.Error())
+			}
+		}
+	}
+}
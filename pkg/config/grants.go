@@ -0,0 +1,83 @@
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ShareGrant gives the listed service accounts use of a SharedSecret or SharedConfigMap. The service accounts are
+// looked up in every namespace named in Namespaces or matched by NamespaceSelector. Grants are part of the
+// administrator owned operator configuration, so that being able to edit a share does not allow handing out
+// access to it.
+type ShareGrant struct {
+	// SharedSecret or SharedConfigMap names the share; exactly one of them is set.
+	SharedSecret      string                `json:"sharedSecret,omitempty"`
+	SharedConfigMap   string                `json:"sharedConfigMap,omitempty"`
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	ServiceAccounts   []string              `json:"serviceAccounts"`
+}
+
+// ShareKey returns the <resource>/<name> of the share of the grant, e.g. sharedsecrets/my-share.
+func (g *ShareGrant) ShareKey() string {
+	if len(g.SharedSecret) > 0 {
+		return "sharedsecrets/" + g.SharedSecret
+	}
+	return "sharedconfigmaps/" + g.SharedConfigMap
+}
+
+func validateShareGrants(fldPath *field.Path, grants []ShareGrant) field.ErrorList {
+	errs := field.ErrorList{}
+	shares := sets.New[string]()
+	for i := range grants {
+		errs = append(errs, grants[i].validate(fldPath.Index(i))...)
+		key := grants[i].ShareKey()
+		if shares.Has(key) {
+			errs = append(errs, field.Duplicate(fldPath.Index(i), key))
+		}
+		shares.Insert(key)
+	}
+	return errs
+}
+
+func (g *ShareGrant) validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	switch {
+	case len(g.SharedSecret) > 0 && len(g.SharedConfigMap) > 0:
+		errs = append(errs, field.Invalid(fldPath, g.ShareKey(), "only one of sharedSecret and sharedConfigMap may be set"))
+	case len(g.SharedSecret) > 0:
+		for _, msg := range validation.IsDNS1123Subdomain(g.SharedSecret) {
+			errs = append(errs, field.Invalid(fldPath.Child("sharedSecret"), g.SharedSecret, msg))
+		}
+	case len(g.SharedConfigMap) > 0:
+		for _, msg := range validation.IsDNS1123Subdomain(g.SharedConfigMap) {
+			errs = append(errs, field.Invalid(fldPath.Child("sharedConfigMap"), g.SharedConfigMap, msg))
+		}
+	default:
+		errs = append(errs, field.Required(fldPath, "sharedSecret or sharedConfigMap must be set"))
+	}
+	if len(g.Namespaces) == 0 && g.NamespaceSelector == nil {
+		errs = append(errs, field.Required(fldPath.Child("namespaces"), "namespaces or namespaceSelector must be set"))
+	}
+	for i, ns := range g.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(fldPath.Child("namespaces").Index(i), ns, msg))
+		}
+	}
+	if g.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(g.NamespaceSelector); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("namespaceSelector"), g.NamespaceSelector, err.Error()))
+		}
+	}
+	if len(g.ServiceAccounts) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("serviceAccounts"), ""))
+	}
+	for i, sa := range g.ServiceAccounts {
+		for _, msg := range validation.IsDNS1123Subdomain(sa) {
+			errs = append(errs, field.Invalid(fldPath.Child("serviceAccounts").Index(i), sa, msg))
+		}
+	}
+	return errs
+}
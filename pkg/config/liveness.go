@@ -0,0 +1,40 @@
+package config
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// LivenessProbeConfig overrides the thresholds of the liveness probe of the driver container, which the
+// csi-liveness-probe sidecar answers by calling the driver over its socket. Unset thresholds keep the values of
+// node.yaml.
+type LivenessProbeConfig struct {
+	// InitialDelaySeconds is how long the kubelet waits after the driver has started before the first probe.
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+	// PeriodSeconds is how often the driver is probed.
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+	// TimeoutSeconds is how long a probe may take, both for the kubelet and for the sidecar calling the driver.
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// FailureThreshold is how many consecutive probes must fail before the driver is restarted.
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// Validate returns the invalid fields of the liveness probe configuration, rooted at fldPath.
+func (c *LivenessProbeConfig) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if c.InitialDelaySeconds != nil && *c.InitialDelaySeconds < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("initialDelaySeconds"), *c.InitialDelaySeconds, "must not be negative"))
+	}
+	for _, threshold := range []struct {
+		name  string
+		value *int32
+	}{
+		{name: "periodSeconds", value: c.PeriodSeconds},
+		{name: "timeoutSeconds", value: c.TimeoutSeconds},
+		{name: "failureThreshold", value: c.FailureThreshold},
+	} {
+		if threshold.value != nil && *threshold.value < 1 {
+			errs = append(errs, field.Invalid(fldPath.Child(threshold.name), *threshold.value, "must be at least 1"))
+		}
+	}
+	return errs
+}
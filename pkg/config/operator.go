@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// OperatorConfigMapName is the optional, administrator owned ConfigMap in the operator namespace that
+	// carries the operator configuration under ConfigKey.
+	OperatorConfigMapName = "csi-driver-shared-resource-operator-config"
+)
+
+// OperatorConfig is the configuration of the operator itself. Every section is optional; an absent
+// operator ConfigMap is equivalent to an empty OperatorConfig.
+type OperatorConfig struct {
+	// DriverConfig is rendered by the operator into the driver's csi-driver-shared-resource-config ConfigMap.
+	DriverConfig *Config `json:"driverConfig,omitempty"`
+	// SkipValidationNamespaces lists namespaces, in addition to the operator namespace, that the operator keeps
+	// labelled with csi.sharedresource.openshift.io/skip-validation so the webhook does not validate their pods.
+	SkipValidationNamespaces []string `json:"skipValidationNamespaces,omitempty"`
+	// ReservedSharedSecretNames maps SharedSecret names to the Secret, in namespace:name form, that is the only
+	// one a share of that name may reference. openshift-etc-pki-entitlement is always reserved.
+	ReservedSharedSecretNames map[string]string `json:"reservedSharedSecretNames,omitempty"`
+	// ReservedSharedConfigMapNames maps SharedConfigMap names to the ConfigMap, in namespace:name form, that is
+	// the only one a share of that name may reference.
+	ReservedSharedConfigMapNames map[string]string `json:"reservedSharedConfigMapNames,omitempty"`
+	// EntitlementSubjects are bound to the ClusterRole granting use of the openshift-etc-pki-entitlement
+	// SharedSecret, e.g. the builder service accounts of the namespaces running entitled builds.
+	EntitlementSubjects []rbacv1.Subject `json:"entitlementSubjects,omitempty"`
+	// ShareGrants give service accounts use of shares through generated Roles and RoleBindings.
+	ShareGrants []ShareGrant `json:"shareGrants,omitempty"`
+	// OrphanedShares decides what happens to shares whose backing Secret or ConfigMap no longer exists.
+	OrphanedShares *OrphanedSharesConfig `json:"orphanedShares,omitempty"`
+	// Webhook configures the ValidatingWebhookConfiguration of the share validation webhook.
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+	// NodePlacement decides which nodes run the driver DaemonSet; it runs on every untainted node when unset.
+	NodePlacement *NodePlacementConfig `json:"nodePlacement,omitempty"`
+	// Resources overrides the resource requests and limits of the driver and webhook containers.
+	Resources *ResourcesConfig `json:"resources,omitempty"`
+	// LivenessProbe overrides the thresholds of the liveness probe of the driver container.
+	LivenessProbe *LivenessProbeConfig `json:"livenessProbe,omitempty"`
+}
+
+// ParseOperatorConfig unmarshals and validates the operator configuration in data. Unknown keys are
+// rejected, so that a misspelt setting is reported instead of being silently ignored.
+func ParseOperatorConfig(data []byte) (*OperatorConfig, error) {
+	cfg := &OperatorConfig{}
+	if err := UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing operator configuration: %s", err)
+	}
+	if err := cfg.Validate().ToAggregate(); err != nil {
+		return nil, fmt.Errorf("invalid operator configuration: %s", err)
+	}
+	return cfg, nil
+}
+
+// Validate returns the invalid fields of the operator configuration.
+func (c *OperatorConfig) Validate() field.ErrorList {
+	errs := field.ErrorList{}
+	if c.DriverConfig != nil {
+		errs = append(errs, c.DriverConfig.Validate(field.NewPath("driverConfig"))...)
+	}
+	for i, ns := range c.SkipValidationNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(field.NewPath("skipValidationNamespaces").Index(i), ns, msg))
+		}
+	}
+	errs = append(errs, validateReservedNames(field.NewPath("reservedSharedSecretNames"), defaultReservedSharedSecretNames, c.ReservedSharedSecretNames)...)
+	errs = append(errs, validateReservedNames(field.NewPath("reservedSharedConfigMapNames"), defaultReservedSharedConfigMapNames, c.ReservedSharedConfigMapNames)...)
+	errs = append(errs, validateSubjects(field.NewPath("entitlementSubjects"), c.EntitlementSubjects)...)
+	errs = append(errs, validateShareGrants(field.NewPath("shareGrants"), c.ShareGrants)...)
+	if c.OrphanedShares != nil {
+		errs = append(errs, c.OrphanedShares.Validate(field.NewPath("orphanedShares"))...)
+	}
+	if c.Webhook != nil {
+		errs = append(errs, c.Webhook.Validate(field.NewPath("webhook"))...)
+	}
+	if c.NodePlacement != nil {
+		errs = append(errs, c.NodePlacement.Validate(field.NewPath("nodePlacement"))...)
+	}
+	if c.Resources != nil {
+		errs = append(errs, c.Resources.Validate(field.NewPath("resources"))...)
+	}
+	if c.LivenessProbe != nil {
+		errs = append(errs, c.LivenessProbe.Validate(field.NewPath("livenessProbe"))...)
+	}
+	return errs
+}
+
+func validateSubjects(fldPath *field.Path, subjects []rbacv1.Subject) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, subject := range subjects {
+		if len(subject.Name) == 0 {
+			errs = append(errs, field.Required(fldPath.Index(i).Child("name"), ""))
+		}
+		switch subject.Kind {
+		case rbacv1.ServiceAccountKind:
+			if len(subject.Namespace) == 0 {
+				errs = append(errs, field.Required(fldPath.Index(i).Child("namespace"), "a ServiceAccount subject needs a namespace"))
+			}
+		case rbacv1.UserKind, rbacv1.GroupKind:
+		default:
+			errs = append(errs, field.NotSupported(fldPath.Index(i).Child("kind"), subject.Kind, []string{rbacv1.ServiceAccountKind, rbacv1.UserKind, rbacv1.GroupKind}))
+		}
+	}
+	return errs
+}
+
+// GetOperatorConfig reads the operator configuration from the OperatorConfigMapName ConfigMap through
+// lister. A missing ConfigMap, or one without the ConfigKey entry, yields an empty configuration.
+func GetOperatorConfig(lister corev1listers.ConfigMapNamespaceLister) (*OperatorConfig, error) {
+	cm, err := lister.Get(OperatorConfigMapName)
+	if kerrors.IsNotFound(err) {
+		return &OperatorConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error reading ConfigMap %q: %s", OperatorConfigMapName, err)
+	}
+	data, ok := cm.Data[ConfigKey]
+	if !ok {
+		return &OperatorConfig{}, nil
+	}
+	return ParseOperatorConfig([]byte(data))
+}
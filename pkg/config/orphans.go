@@ -0,0 +1,76 @@
+package config
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// OrphanPolicy is what the operator does with a share whose backing Secret or ConfigMap has been missing for
+// longer than the grace period.
+type OrphanPolicy string
+
+const (
+	// OrphanPolicyIgnore only reports orphaned shares, through events, conditions and metrics.
+	OrphanPolicyIgnore OrphanPolicy = "Ignore"
+	// OrphanPolicyAnnotate additionally sets OrphanedAnnotation on orphaned shares.
+	OrphanPolicyAnnotate OrphanPolicy = "Annotate"
+	// OrphanPolicyDelete deletes orphaned shares.
+	OrphanPolicyDelete OrphanPolicy = "Delete"
+
+	// OrphanedAnnotation records, on shares annotated by OrphanPolicyAnnotate, since when the backing
+	// resource has been missing. It is removed once the backing resource exists again.
+	OrphanedAnnotation = "sharedresource.openshift.io/orphaned-since"
+
+	// DefaultOrphanGracePeriod is the grace period used when none is configured.
+	DefaultOrphanGracePeriod = time.Hour
+)
+
+// OrphanedSharesConfig decides what happens to shares whose backing resource, or its namespace, is gone.
+type OrphanedSharesConfig struct {
+	// Policy is one of Ignore, the default, Annotate or Delete.
+	Policy OrphanPolicy `json:"policy,omitempty"`
+	// GracePeriod is how long the backing resource must have been missing before Policy applies, e.g. "24h".
+	GracePeriod string `json:"gracePeriod,omitempty"`
+}
+
+// GetOrphanPolicy returns the configured orphan policy, OrphanPolicyIgnore when unset.
+func (c *OperatorConfig) GetOrphanPolicy() OrphanPolicy {
+	if c.OrphanedShares == nil || len(c.OrphanedShares.Policy) == 0 {
+		return OrphanPolicyIgnore
+	}
+	return c.OrphanedShares.Policy
+}
+
+// GetOrphanGracePeriod returns the parsed grace period, or DefaultOrphanGracePeriod when it is unset or
+// cannot be parsed.
+func (c *OperatorConfig) GetOrphanGracePeriod() time.Duration {
+	if c.OrphanedShares == nil {
+		return DefaultOrphanGracePeriod
+	}
+	d, err := time.ParseDuration(c.OrphanedShares.GracePeriod)
+	if err != nil || d < 0 {
+		return DefaultOrphanGracePeriod
+	}
+	return d
+}
+
+// Validate returns the invalid fields of the orphaned shares configuration, rooted at fldPath.
+func (c *OrphanedSharesConfig) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	switch c.Policy {
+	case "", OrphanPolicyIgnore, OrphanPolicyAnnotate, OrphanPolicyDelete:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("policy"), c.Policy,
+			[]string{string(OrphanPolicyIgnore), string(OrphanPolicyAnnotate), string(OrphanPolicyDelete)}))
+	}
+	if len(c.GracePeriod) > 0 {
+		d, err := time.ParseDuration(c.GracePeriod)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("gracePeriod"), c.GracePeriod, err.Error()))
+		} else if d < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("gracePeriod"), c.GracePeriod, "must not be negative"))
+		}
+	}
+	return errs
+}
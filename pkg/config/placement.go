@@ -0,0 +1,113 @@
+package config
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// NodePlacementConfig decides which nodes run the driver DaemonSet. It is rendered into the pod template as is,
+// replacing what node.yaml sets.
+type NodePlacementConfig struct {
+	// NodeSelector restricts the driver to nodes carrying all of these labels.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations let the driver run on nodes with matching taints.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity further restricts, or orders, the nodes the driver runs on.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+}
+
+// Validate returns the invalid fields of the node placement configuration, rooted at fldPath.
+func (c *NodePlacementConfig) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for key, value := range c.NodeSelector {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(fldPath.Child("nodeSelector"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, field.Invalid(fldPath.Child("nodeSelector").Key(key), value, msg))
+		}
+	}
+	for i, toleration := range c.Tolerations {
+		errs = append(errs, validateToleration(fldPath.Child("tolerations").Index(i), toleration)...)
+	}
+	if c.Affinity != nil && c.Affinity.NodeAffinity != nil {
+		nodeAffinity := c.Affinity.NodeAffinity
+		fldPath := fldPath.Child("affinity", "nodeAffinity")
+		if required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			fldPath := fldPath.Child("requiredDuringSchedulingIgnoredDuringExecution")
+			if len(required.NodeSelectorTerms) == 0 {
+				errs = append(errs, field.Required(fldPath.Child("nodeSelectorTerms"), "must have at least one node selector term"))
+			}
+			for i, term := range required.NodeSelectorTerms {
+				errs = append(errs, validateNodeSelectorTerm(fldPath.Child("nodeSelectorTerms").Index(i), term)...)
+			}
+		}
+		for i, preferred := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			fldPath := fldPath.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+			if preferred.Weight < 1 || preferred.Weight > 100 {
+				errs = append(errs, field.Invalid(fldPath.Child("weight"), preferred.Weight, "must be between 1 and 100"))
+			}
+			errs = append(errs, validateNodeSelectorTerm(fldPath.Child("preference"), preferred.Preference)...)
+		}
+	}
+	return errs
+}
+
+func validateToleration(fldPath *field.Path, toleration corev1.Toleration) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(toleration.Key) > 0 {
+		for _, msg := range validation.IsQualifiedName(toleration.Key) {
+			errs = append(errs, field.Invalid(fldPath.Child("key"), toleration.Key, msg))
+		}
+	}
+	switch toleration.Operator {
+	case "", corev1.TolerationOpEqual:
+		if len(toleration.Key) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("operator"), toleration.Operator, "must be Exists when key is empty"))
+		}
+	case corev1.TolerationOpExists:
+		if len(toleration.Value) > 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("value"), toleration.Value, "must be empty when operator is Exists"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("operator"), toleration.Operator,
+			[]string{string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists)}))
+	}
+	switch toleration.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("effect"), toleration.Effect,
+			[]string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}))
+	}
+	return errs
+}
+
+func validateNodeSelectorTerm(fldPath *field.Path, term corev1.NodeSelectorTerm) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, requirement := range term.MatchExpressions {
+		fldPath := fldPath.Child("matchExpressions").Index(i)
+		for _, msg := range validation.IsQualifiedName(requirement.Key) {
+			errs = append(errs, field.Invalid(fldPath.Child("key"), requirement.Key, msg))
+		}
+		switch requirement.Operator {
+		case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+			if len(requirement.Values) == 0 {
+				errs = append(errs, field.Required(fldPath.Child("values"), "must be specified for In and NotIn"))
+			}
+		case corev1.NodeSelectorOpExists, corev1.NodeSelectorOpDoesNotExist:
+			if len(requirement.Values) > 0 {
+				errs = append(errs, field.Forbidden(fldPath.Child("values"), "must be empty for Exists and DoesNotExist"))
+			}
+		case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+			if len(requirement.Values) != 1 {
+				errs = append(errs, field.Required(fldPath.Child("values"), "must have a single value for Gt and Lt"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(fldPath.Child("operator"), requirement.Operator,
+				[]string{string(corev1.NodeSelectorOpIn), string(corev1.NodeSelectorOpNotIn), string(corev1.NodeSelectorOpExists),
+					string(corev1.NodeSelectorOpDoesNotExist), string(corev1.NodeSelectorOpGt), string(corev1.NodeSelectorOpLt)}))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// ReservedSharedSecretNamesEnv and ReservedSharedConfigMapNamesEnv are the environment variables through
+	// which the driver and the webhook receive the reserved share names.
+	ReservedSharedSecretNamesEnv    = "RESERVED_SHARED_SECRET_NAMES"
+	ReservedSharedConfigMapNamesEnv = "RESERVED_SHARED_CONFIGMAP_NAMES"
+
+	// reservedNamesSeparator separates the "share: namespace:name" entries of the environment variables
+	reservedNamesSeparator = ";"
+)
+
+var (
+	// the entitlement share is provisioned by the operator, so its name is always reserved
+	defaultReservedSharedSecretNames = map[string]string{
+		"openshift-etc-pki-entitlement": "openshift-config-managed:etc-pki-entitlement",
+	}
+	defaultReservedSharedConfigMapNames = map[string]string{}
+)
+
+// GetReservedSharedSecretNames returns the reserved SharedSecret names, the operator defaults included,
+// mapped to the only Secret a share of that name may reference.
+func (c *OperatorConfig) GetReservedSharedSecretNames() map[string]types.NamespacedName {
+	return reservedNames(defaultReservedSharedSecretNames, c.ReservedSharedSecretNames)
+}
+
+// GetReservedSharedConfigMapNames returns the reserved SharedConfigMap names, the operator defaults included,
+// mapped to the only ConfigMap a share of that name may reference.
+func (c *OperatorConfig) GetReservedSharedConfigMapNames() map[string]types.NamespacedName {
+	return reservedNames(defaultReservedSharedConfigMapNames, c.ReservedSharedConfigMapNames)
+}
+
+// FormatReservedNames renders reserved in the "share: namespace:name" format, entries separated by
+// semicolons, that the driver and the webhook read from their environment. Entries are sorted so the
+// rendered value only changes with the reservations.
+func FormatReservedNames(reserved map[string]types.NamespacedName) string {
+	shares := make([]string, 0, len(reserved))
+	for share := range reserved {
+		shares = append(shares, share)
+	}
+	sort.Strings(shares)
+	entries := make([]string, 0, len(shares))
+	for _, share := range shares {
+		entries = append(entries, fmt.Sprintf("%s: %s:%s", share, reserved[share].Namespace, reserved[share].Name))
+	}
+	return strings.Join(entries, reservedNamesSeparator)
+}
+
+func reservedNames(defaults, configured map[string]string) map[string]types.NamespacedName {
+	reserved := map[string]types.NamespacedName{}
+	for _, names := range []map[string]string{defaults, configured} {
+		for share, backing := range names {
+			if nn, err := parseBackingResource(backing); err == nil {
+				reserved[share] = nn
+			}
+		}
+	}
+	return reserved
+}
+
+// parseBackingResource parses the namespace:name form of a reserved share's backing resource.
+func parseBackingResource(value string) (types.NamespacedName, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return types.NamespacedName{}, fmt.Errorf("must be in the form namespace:name")
+	}
+	var msgs []string
+	for _, msg := range validation.IsDNS1123Label(parts[0]) {
+		msgs = append(msgs, "namespace "+msg)
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(parts[1]) {
+		msgs = append(msgs, "name "+msg)
+	}
+	if len(msgs) > 0 {
+		return types.NamespacedName{}, fmt.Errorf("%s", strings.Join(msgs, ", "))
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nil
+}
+
+func validateReservedNames(fldPath *field.Path, defaults, configured map[string]string) field.ErrorList {
+	errs := field.ErrorList{}
+	for share, backing := range configured {
+		for _, msg := range validation.IsDNS1123Subdomain(share) {
+			errs = append(errs, field.Invalid(fldPath.Key(share), share, msg))
+		}
+		if _, err := parseBackingResource(backing); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Key(share), backing, err.Error()))
+			continue
+		}
+		if reserved, ok := defaults[share]; ok && reserved != backing {
+			errs = append(errs, field.Invalid(fldPath.Key(share), backing, fmt.Sprintf("is reserved by the operator for %s", reserved)))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ResourcesConfig overrides the resource requests and limits of the operand containers. Only the resources it
+// names are overridden; the others keep the values of the manifests.
+type ResourcesConfig struct {
+	// Hostpath is the driver container of the node DaemonSet.
+	Hostpath *corev1.ResourceRequirements `json:"hostpath,omitempty"`
+	// NodeDriverRegistrar is the registrar sidecar of the node DaemonSet.
+	NodeDriverRegistrar *corev1.ResourceRequirements `json:"nodeDriverRegistrar,omitempty"`
+	// LivenessProbe is the csi-liveness-probe sidecar of the node DaemonSet.
+	LivenessProbe *corev1.ResourceRequirements `json:"livenessProbe,omitempty"`
+	// Webhook is the container of the webhook Deployment.
+	Webhook *corev1.ResourceRequirements `json:"webhook,omitempty"`
+}
+
+var supportedResources = []string{string(corev1.ResourceCPU), string(corev1.ResourceMemory), string(corev1.ResourceEphemeralStorage)}
+
+// Validate returns the invalid fields of the resources configuration, rooted at fldPath.
+func (c *ResourcesConfig) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	errs = append(errs, ValidateResourceRequirements(fldPath.Child("hostpath"), c.Hostpath)...)
+	errs = append(errs, ValidateResourceRequirements(fldPath.Child("nodeDriverRegistrar"), c.NodeDriverRegistrar)...)
+	errs = append(errs, ValidateResourceRequirements(fldPath.Child("livenessProbe"), c.LivenessProbe)...)
+	errs = append(errs, ValidateResourceRequirements(fldPath.Child("webhook"), c.Webhook)...)
+	return errs
+}
+
+// ValidateResourceRequirements returns the invalid fields of requirements, rooted at fldPath: unsupported
+// resources, negative quantities and requests above their limit.
+func ValidateResourceRequirements(fldPath *field.Path, requirements *corev1.ResourceRequirements) field.ErrorList {
+	errs := field.ErrorList{}
+	if requirements == nil {
+		return errs
+	}
+	for _, list := range []struct {
+		name      string
+		resources corev1.ResourceList
+	}{
+		{name: "requests", resources: requirements.Requests},
+		{name: "limits", resources: requirements.Limits},
+	} {
+		for name, quantity := range list.resources {
+			fldPath := fldPath.Child(list.name).Key(string(name))
+			switch name {
+			case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+			default:
+				errs = append(errs, field.NotSupported(fldPath, name, supportedResources))
+				continue
+			}
+			if quantity.Sign() < 0 {
+				errs = append(errs, field.Invalid(fldPath, quantity.String(), "must not be negative"))
+			}
+		}
+	}
+	for name, request := range requirements.Requests {
+		if limit, ok := requirements.Limits[name]; ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("requests").Key(string(name)), request.String(),
+				fmt.Sprintf("must not be greater than the limit of %s", limit.String())))
+		}
+	}
+	if len(requirements.Claims) > 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("claims"), "resource claims are not supported"))
+	}
+	return errs
+}
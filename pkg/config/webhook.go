@@ -0,0 +1,109 @@
+package config
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// WebhookFailurePolicy is how the API server treats requests when the validating webhook cannot be called.
+type WebhookFailurePolicy string
+
+const (
+	// WebhookFailurePolicyIgnore admits requests the webhook could not validate.
+	WebhookFailurePolicyIgnore WebhookFailurePolicy = "Ignore"
+	// WebhookFailurePolicyFail rejects requests the webhook could not validate.
+	WebhookFailurePolicyFail WebhookFailurePolicy = "Fail"
+	// WebhookFailurePolicyAdaptive uses Fail while the webhook is available, and falls back to Ignore once it has
+	// been unavailable for the unavailable window.
+	WebhookFailurePolicyAdaptive WebhookFailurePolicy = "Adaptive"
+
+	// DefaultWebhookTimeoutSeconds is the webhook timeout used when none is configured.
+	DefaultWebhookTimeoutSeconds int32 = 10
+	// DefaultWebhookUnavailableWindow is the unavailable window used when none is configured.
+	DefaultWebhookUnavailableWindow = 5 * time.Minute
+)
+
+// WebhookConfig configures the ValidatingWebhookConfiguration of the share validation webhook.
+type WebhookConfig struct {
+	// FailurePolicy is one of Ignore, the default, Fail or Adaptive.
+	FailurePolicy WebhookFailurePolicy `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is how long the API server waits for the webhook, between 1 and 30 seconds.
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// UnavailableWindow is, with the Adaptive failure policy, how long the webhook may be unavailable before the
+	// failure policy falls back to Ignore, e.g. "5m".
+	UnavailableWindow string `json:"unavailableWindow,omitempty"`
+	// ExcludedNamespaces are namespaces whose requests are never sent to the webhook, in addition to the run-level
+	// and skip-validation namespaces.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	// NamespaceSelector is ANDed with the built-in namespace exclusions: only requests in namespaces it matches are
+	// sent to the webhook. Namespaces are excluded by label with NotIn or DoesNotExist requirements.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ValidateShareUpdates sends UPDATE requests on SharedSecrets and SharedConfigMaps to the webhook, not only
+	// CREATE requests.
+	ValidateShareUpdates bool `json:"validateShareUpdates,omitempty"`
+}
+
+// GetWebhookFailurePolicy returns the configured failure policy, WebhookFailurePolicyIgnore when unset.
+func (c *OperatorConfig) GetWebhookFailurePolicy() WebhookFailurePolicy {
+	if c.Webhook == nil || len(c.Webhook.FailurePolicy) == 0 {
+		return WebhookFailurePolicyIgnore
+	}
+	return c.Webhook.FailurePolicy
+}
+
+// GetWebhookTimeoutSeconds returns the configured timeout, DefaultWebhookTimeoutSeconds when unset.
+func (c *OperatorConfig) GetWebhookTimeoutSeconds() int32 {
+	if c.Webhook == nil || c.Webhook.TimeoutSeconds == nil {
+		return DefaultWebhookTimeoutSeconds
+	}
+	return *c.Webhook.TimeoutSeconds
+}
+
+// GetWebhookUnavailableWindow returns the parsed unavailable window, or DefaultWebhookUnavailableWindow when it
+// is unset or cannot be parsed.
+func (c *OperatorConfig) GetWebhookUnavailableWindow() time.Duration {
+	if c.Webhook == nil {
+		return DefaultWebhookUnavailableWindow
+	}
+	d, err := time.ParseDuration(c.Webhook.UnavailableWindow)
+	if err != nil || d < 0 {
+		return DefaultWebhookUnavailableWindow
+	}
+	return d
+}
+
+// Validate returns the invalid fields of the webhook configuration, rooted at fldPath.
+func (c *WebhookConfig) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	switch c.FailurePolicy {
+	case "", WebhookFailurePolicyIgnore, WebhookFailurePolicyFail, WebhookFailurePolicyAdaptive:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("failurePolicy"), c.FailurePolicy,
+			[]string{string(WebhookFailurePolicyIgnore), string(WebhookFailurePolicyFail), string(WebhookFailurePolicyAdaptive)}))
+	}
+	if c.TimeoutSeconds != nil && (*c.TimeoutSeconds < 1 || *c.TimeoutSeconds > 30) {
+		errs = append(errs, field.Invalid(fldPath.Child("timeoutSeconds"), *c.TimeoutSeconds, "must be between 1 and 30 seconds"))
+	}
+	if len(c.UnavailableWindow) > 0 {
+		d, err := time.ParseDuration(c.UnavailableWindow)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("unavailableWindow"), c.UnavailableWindow, err.Error()))
+		} else if d < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("unavailableWindow"), c.UnavailableWindow, "must not be negative"))
+		}
+	}
+	for i, ns := range c.ExcludedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(fldPath.Child("excludedNamespaces").Index(i), ns, msg))
+		}
+	}
+	if c.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(c.NamespaceSelector); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("namespaceSelector"), c.NamespaceSelector, err.Error()))
+		}
+	}
+	return errs
+}
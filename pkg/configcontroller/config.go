@@ -0,0 +1,136 @@
+package configcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
+)
+
+const (
+	controllerName = "SharedResourceDriverConfigController"
+	configMapAsset = "config_configmap.yaml"
+
+	// DriverConfigDegradedConditionType is reported on the ClusterCSIDriver when the driver configuration
+	// requested through the operator configuration is invalid or cannot be applied.
+	DriverConfigDegradedConditionType = "DriverConfigDegraded"
+
+	resyncInterval = 10 * time.Minute
+)
+
+// driverConfigController renders the driverConfig section of the operator configuration into the
+// driver's configuration ConfigMap. The ConfigMap is created from the embedded default when it is missing,
+// and any key the operator configuration does not set is left as found, so hand-made settings survive.
+type driverConfigController struct {
+	kubeClient      kubernetes.Interface
+	operatorClient  v1helpers.OperatorClient
+	configMapLister corev1listers.ConfigMapNamespaceLister
+}
+
+func NewDriverConfigController(kubeClient kubernetes.Interface,
+	operatorClient v1helpers.OperatorClient,
+	configMapInformer corev1informers.ConfigMapInformer,
+	recorder events.Recorder) factory.Controller {
+
+	c := &driverConfigController{
+		kubeClient:      kubeClient,
+		operatorClient:  operatorClient,
+		configMapLister: configMapInformer.Lister().ConfigMaps(config.DefaultNamespace),
+	}
+	return factory.New().WithFilteredEventsInformers(
+		factory.NamesFilter(config.DriverConfigMapName, config.OperatorConfigMapName),
+		configMapInformer.Informer(),
+	).WithSync(
+		metrics.InstrumentSync(controllerName, c.sync),
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		metrics.InstrumentRecorder(controllerName, recorder.WithComponentSuffix("shared-resource-driver-config-controller")),
+	)
+}
+
+func (c *driverConfigController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	opConfig, err := config.GetOperatorConfig(c.configMapLister)
+	if err != nil {
+		// nothing will change until the operator configuration is fixed, so report it rather than retrying
+		syncContext.Recorder().Warningf("InvalidConfiguration", "%s", err)
+		return c.updateCondition(ctx, "InvalidConfiguration", err)
+	}
+
+	required, err := c.requiredConfigMap(opConfig.DriverConfig)
+	if err != nil {
+		return c.updateCondition(ctx, "InvalidDriverConfiguration", err)
+	}
+
+	if _, _, err := resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), syncContext.Recorder(), required); err != nil {
+		err = fmt.Errorf("error applying ConfigMap %q: %w", required.Name, err)
+		if condErr := c.updateCondition(ctx, "ApplyFailed", err); condErr != nil {
+			return condErr
+		}
+		return err
+	}
+	return c.updateCondition(ctx, "", nil)
+}
+
+// requiredConfigMap returns the driver ConfigMap with the managed driver configuration merged into the
+// config.yaml currently found in the cluster, or into the embedded default when the ConfigMap does not exist.
+func (c *driverConfigController) requiredConfigMap(driverConfig *config.Config) (*corev1.ConfigMap, error) {
+	var required *corev1.ConfigMap
+	existing, err := c.configMapLister.Get(config.DriverConfigMapName)
+	switch {
+	case kerrors.IsNotFound(err):
+		required = &corev1.ConfigMap{}
+		if err := yaml.Unmarshal(assets.MustAsset(configMapAsset), required); err != nil {
+			return nil, fmt.Errorf("error occurred unmarshalling file %q: %s", configMapAsset, err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("unexpected error determining if %q exists: %s", config.DriverConfigMapName, err)
+	default:
+		required = existing.DeepCopy()
+	}
+
+	if driverConfig == nil {
+		return required, nil
+	}
+	data, err := driverConfig.MergeInto([]byte(required.Data[config.ConfigKey]))
+	if err != nil {
+		return nil, err
+	}
+	if required.Data == nil {
+		required.Data = map[string]string{}
+	}
+	required.Data[config.ConfigKey] = string(data)
+	return required, nil
+}
+
+func (c *driverConfigController) updateCondition(ctx context.Context, reason string, err error) error {
+	condition := operatorv1.OperatorCondition{
+		Type:   DriverConfigDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if err != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = err.Error()
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return updateErr
+}
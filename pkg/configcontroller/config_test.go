@@ -0,0 +1,133 @@
+package configcontroller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func configMap(name, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: config.DefaultNamespace},
+		Data:       map[string]string{config.ConfigKey: data},
+	}
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name                 string
+		driverConfigMap      *corev1.ConfigMap
+		operatorConfig       string
+		expectDegraded       bool
+		expectReason         string
+		expectAction         string
+		expectIgnored        []string
+		expectRefresh        bool
+		expectRelistInterval string
+	}{
+		{
+			name:                 "missing ConfigMap is created with the rendered config",
+			operatorConfig:       "driverConfig:\n  shareRelistInterval: 5m\n",
+			expectAction:         "create",
+			expectRefresh:        true,
+			expectRelistInterval: "5m",
+		},
+		{
+			name:                 "existing ConfigMap is updated and keeps unmanaged keys",
+			driverConfigMap:      configMap(config.DriverConfigMapName, "ignoredNamespaces: [kept]\nrefreshResources: true\nshareRelistInterval: 10m\n"),
+			operatorConfig:       "driverConfig:\n  refreshResources: false\n",
+			expectAction:         "update",
+			expectIgnored:        []string{"kept"},
+			expectRelistInterval: "10m",
+		},
+		{
+			name:            "up to date ConfigMap is left alone",
+			driverConfigMap: configMap(config.DriverConfigMapName, "ignoredNamespaces: [kept]\nrefreshResources: true\nshareRelistInterval: 10m\n"),
+		},
+		{
+			name:            "invalid operator config reports Degraded",
+			driverConfigMap: configMap(config.DriverConfigMapName, "shareRelistInterval: 10m\n"),
+			operatorConfig:  "driverConfig:\n  shareRelistInterval: often\n",
+			expectDegraded:  true,
+			expectReason:    "InvalidConfiguration",
+		},
+	} {
+		cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		objects := []runtime.Object{}
+		if test.driverConfigMap != nil {
+			cmIndexer.Add(test.driverConfigMap)
+			objects = append(objects, test.driverConfigMap)
+		}
+		if len(test.operatorConfig) > 0 {
+			cmIndexer.Add(configMap(config.OperatorConfigMapName, test.operatorConfig))
+		}
+
+		client := fake.NewSimpleClientset(objects...)
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &driverConfigController{
+			kubeClient:      client,
+			operatorClient:  operatorClient,
+			configMapLister: corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+		}
+
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, events.NewInMemoryRecorder(controllerName))); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		condition := v1helpers.FindOperatorCondition(status.Conditions, DriverConfigDegradedConditionType)
+		if condition == nil {
+			t.Fatalf("testcase %s: expected condition %s, got %v", test.name, DriverConfigDegradedConditionType, status.Conditions)
+		}
+		if degraded := condition.Status == operatorv1.ConditionTrue; degraded != test.expectDegraded || condition.Reason != test.expectReason {
+			t.Errorf("testcase %s: expected condition %s degraded %v with reason %q, got %v", test.name, DriverConfigDegradedConditionType, test.expectDegraded, test.expectReason, condition)
+		}
+
+		writes := []string{}
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "create" || action.GetVerb() == "update" {
+				writes = append(writes, action.GetVerb())
+			}
+		}
+		if len(test.expectAction) == 0 {
+			if len(writes) > 0 {
+				t.Errorf("testcase %s: expected the ConfigMap to be left alone, got %v", test.name, writes)
+			}
+			continue
+		}
+		if len(writes) != 1 || writes[0] != test.expectAction {
+			t.Fatalf("testcase %s: expected a %s of the ConfigMap, got %v", test.name, test.expectAction, writes)
+		}
+
+		cm, err := client.CoreV1().ConfigMaps(config.DefaultNamespace).Get(context.TODO(), config.DriverConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		rendered, err := config.ParseConfig([]byte(cm.Data[config.ConfigKey]))
+		if err != nil {
+			t.Fatalf("testcase %s: rendered config does not parse: %v", test.name, err)
+		}
+		if rendered.ShareRelistInterval != test.expectRelistInterval {
+			t.Errorf("testcase %s: expected shareRelistInterval %q, got %q", test.name, test.expectRelistInterval, rendered.ShareRelistInterval)
+		}
+		if *rendered.RefreshResources != test.expectRefresh {
+			t.Errorf("testcase %s: expected refreshResources %v, got %v", test.name, test.expectRefresh, *rendered.RefreshResources)
+		}
+		if test.expectIgnored != nil && (len(rendered.IgnoredNamespaces) != len(test.expectIgnored) || rendered.IgnoredNamespaces[0] != test.expectIgnored[0]) {
+			t.Errorf("testcase %s: expected ignoredNamespaces %v, got %v", test.name, test.expectIgnored, rendered.IgnoredNamespaces)
+		}
+	}
+}
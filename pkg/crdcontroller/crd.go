@@ -0,0 +1,128 @@
+package crdcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apiextensionsinformersv1 "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
+)
+
+const (
+	controllerName = "SharedResourceCRDController"
+
+	// CRDsDegradedConditionType is reported on the ClusterCSIDriver when the embedded CRDs cannot be applied.
+	CRDsDegradedConditionType = "CRDsDegraded"
+
+	resyncInterval = 10 * time.Minute
+)
+
+// crdFiles are the embedded CustomResourceDefinition manifests managed by this controller.
+var crdFiles = []string{"0000_10_sharedsecret.crd.yaml", "0000_10_sharedconfigmap.crd.yaml"}
+
+// when we promote out of tech preview and into OCP in general, the shared resource CRDs will be vendored into
+// openshift apiserver and CRD existence will be managed just as it is managed for all the other openshift CRDS;
+// in the interim, this controller keeps the live CRDs in line with the manifests embedded in the operator,
+// recreating them after inadvertent deletes and reverting any drift in versions, schema or printer columns.
+type crdController struct {
+	apiextensionsClient apiextensionsclient.Interface
+	operatorClient      v1helpers.OperatorClient
+	crds                []*apiextensionsv1.CustomResourceDefinition
+}
+
+func NewCRDController(apiextensionsClient apiextensionsclient.Interface,
+	operatorClient v1helpers.OperatorClient,
+	crdInformer apiextensionsinformersv1.CustomResourceDefinitionInformer,
+	recorder events.Recorder) (factory.Controller, error) {
+
+	crds, err := readCRDs()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(crds))
+	for _, crd := range crds {
+		names = append(names, crd.Name)
+	}
+
+	c := &crdController{
+		apiextensionsClient: apiextensionsClient,
+		operatorClient:      operatorClient,
+		crds:                crds,
+	}
+	return factory.New().WithFilteredEventsInformers(
+		factory.NamesFilter(names...),
+		crdInformer.Informer(),
+	).WithSync(
+		metrics.InstrumentSync(controllerName, c.sync),
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		metrics.InstrumentRecorder(controllerName, recorder.WithComponentSuffix("shared-resource-crd-controller")),
+	), nil
+}
+
+func (c *crdController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	var errs []error
+	for _, crd := range c.crds {
+		_, modified, err := resourceapply.ApplyCustomResourceDefinitionV1(ctx, c.apiextensionsClient.ApiextensionsV1(), syncContext.Recorder(), crd)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error applying CustomResourceDefinition %q: %w", crd.Name, err))
+			continue
+		}
+		if modified {
+			klog.Infof("CustomResourceDefinition %q was created or brought back in line with the embedded manifest", crd.Name)
+		}
+	}
+	syncErr := v1helpers.NewMultiLineAggregate(errs)
+
+	condition := operatorv1.OperatorCondition{
+		Type:   CRDsDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if syncErr != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = "ApplyFailed"
+		condition.Message = syncErr.Error()
+	}
+	if _, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition)); err != nil {
+		return err
+	}
+
+	return syncErr
+}
+
+func readCRDs() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	crds := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(crdFiles))
+	for _, file := range crdFiles {
+		data, err := assets.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error occurred reading file %q: %s", file, err)
+		}
+
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(data, crd); err != nil {
+			return nil, fmt.Errorf("error occurred unmarshalling file %q into object: %s", file, err)
+		}
+		if len(strings.TrimSpace(crd.Name)) == 0 {
+			return nil, fmt.Errorf("file %q does not contain a named CustomResourceDefinition", file)
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
@@ -0,0 +1,129 @@
+package crdcontroller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func testCRD(columns ...string) *apiextensionsv1.CustomResourceDefinition {
+	printerColumns := []apiextensionsv1.CustomResourceColumnDefinition{}
+	for _, c := range columns {
+		printerColumns = append(printerColumns, apiextensionsv1.CustomResourceColumnDefinition{
+			Name:     c,
+			Type:     "string",
+			JSONPath: ".spec." + c,
+		})
+	}
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "sharedsecrets.sharedresource.openshift.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "sharedresource.openshift.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural: "sharedsecrets",
+				Kind:   "SharedSecret",
+			},
+			Scope: apiextensionsv1.ClusterScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:                     "v1alpha1",
+					Served:                   true,
+					Storage:                  true,
+					AdditionalPrinterColumns: printerColumns,
+				},
+			},
+		},
+	}
+}
+
+// asDefaulted mimics the defaulting the API server applies to a stored CustomResourceDefinition.
+func asDefaulted(crd *apiextensionsv1.CustomResourceDefinition) *apiextensionsv1.CustomResourceDefinition {
+	crd.Spec.Names.Singular = "sharedsecret"
+	crd.Spec.Names.ListKind = "SharedSecretList"
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+	return crd
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		existing        []runtime.Object
+		applyErr        error
+		expectedActions []string
+		expectDegraded  operatorv1.ConditionStatus
+		expectErr       bool
+	}{
+		{
+			name:            "missing CRD is created",
+			expectedActions: []string{"get", "create"},
+			expectDegraded:  operatorv1.ConditionFalse,
+		},
+		{
+			name:            "CRD in sync is left alone",
+			existing:        []runtime.Object{asDefaulted(testCRD("secretRef"))},
+			expectedActions: []string{"get"},
+			expectDegraded:  operatorv1.ConditionFalse,
+		},
+		{
+			name:            "drifted CRD is updated",
+			existing:        []runtime.Object{asDefaulted(testCRD("secretRef", "hand-added"))},
+			expectedActions: []string{"get", "update"},
+			expectDegraded:  operatorv1.ConditionFalse,
+		},
+		{
+			name:            "apply failure reports degraded",
+			applyErr:        fmt.Errorf("boom"),
+			expectedActions: []string{"get"},
+			expectDegraded:  operatorv1.ConditionTrue,
+			expectErr:       true,
+		},
+	} {
+		client := fake.NewSimpleClientset(test.existing...)
+		if test.applyErr != nil {
+			client.PrependReactor("get", "customresourcedefinitions", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, test.applyErr
+			})
+		}
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &crdController{
+			apiextensionsClient: client,
+			operatorClient:      operatorClient,
+			crds:                []*apiextensionsv1.CustomResourceDefinition{testCRD("secretRef")},
+		}
+
+		err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, events.NewInMemoryRecorder(controllerName)))
+		if test.expectErr != (err != nil) {
+			t.Errorf("testcase %s: expected error %v, got %v", test.name, test.expectErr, err)
+		}
+
+		actions := client.Actions()
+		if len(actions) != len(test.expectedActions) {
+			t.Fatalf("testcase %s: expected actions %v, got %v", test.name, test.expectedActions, actions)
+		}
+		for i, verb := range test.expectedActions {
+			if actions[i].GetVerb() != verb {
+				t.Errorf("testcase %s: expected action %d to be %q, got %q", test.name, i, verb, actions[i].GetVerb())
+			}
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		condition := v1helpers.FindOperatorCondition(status.Conditions, CRDsDegradedConditionType)
+		if condition == nil {
+			t.Fatalf("testcase %s: condition %s was not set", test.name, CRDsDegradedConditionType)
+		}
+		if condition.Status != test.expectDegraded {
+			t.Errorf("testcase %s: expected condition status %s, got %s", test.name, test.expectDegraded, condition.Status)
+		}
+	}
+}
@@ -3,56 +3,115 @@ package deploymentcontroller
 import (
 	"bytes"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
 	configinformers "github.com/openshift/client-go/config/informers/externalversions"
 	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/hooks"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/csi/csidrivercontrollerservicecontroller"
 	"github.com/openshift/library-go/pkg/operator/deploymentcontroller"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/loglevel"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	defaultNamespace                    = "openshift-cluster-csi-drivers"
+	controllerName                      = "SharedResourceCSIDriverWebhookController"
 	envSharedResourceDriverWebhookImage = "WEBHOOK_IMAGE"
 	infraConfigName                     = "cluster"
 	webhookSecretName                   = "shared-resource-csi-driver-webhook-serving-cert"
 )
 
+// NewWebHookDeploymentController manages the webhook Deployment. When hostedControlPlaneNamespace is set, the
+// Deployment is applied through kubeClient to that namespace of the management cluster, and
+// controlPlaneInformers watch that namespace; otherwise both point at the cluster the operator manages.
 func NewWebHookDeploymentController(kubeClient kubernetes.Interface,
 	operatorClient v1helpers.OperatorClientWithFinalizers,
 	kubeInformersForNamespaces v1helpers.KubeInformersForNamespaces,
+	controlPlaneInformers v1helpers.KubeInformersForNamespaces,
+	hostedControlPlaneNamespace string,
 	configInformer configinformers.SharedInformerFactory,
-	recorder events.Recorder) factory.Controller {
+	recorder events.Recorder) (factory.Controller, error) {
 
+	namespace := config.DefaultNamespace
+	if len(hostedControlPlaneNamespace) > 0 {
+		namespace = hostedControlPlaneNamespace
+	}
 	nodeLister := kubeInformersForNamespaces.InformersFor("").Core().V1().Nodes().Lister()
-	secretInformer := kubeInformersForNamespaces.InformersFor(defaultNamespace).Core().V1().Secrets()
+	secretInformer := controlPlaneInformers.InformersFor(namespace).Core().V1().Secrets()
+	configMapInformer := kubeInformersForNamespaces.InformersFor(config.DefaultNamespace).Core().V1().ConfigMaps()
 
-	return deploymentcontroller.NewDeploymentController(
-		"SharedResourceCSIDriverWebhookController",
-		assets.MustAsset("webhook/deployment.yaml"),
-		recorder,
-		operatorClient,
-		kubeClient,
-		kubeInformersForNamespaces.InformersFor(defaultNamespace).Apps().V1().Deployments(),
-		[]factory.Informer{
-			secretInformer.Informer(),
-			configInformer.Config().V1().Infrastructures().Informer(),
-		},
-		[]deploymentcontroller.ManifestHookFunc{
-			replaceAll("${WEBHOOK_IMAGE}", os.Getenv(envSharedResourceDriverWebhookImage)),
-		},
+	manifestHooks := []deploymentcontroller.ManifestHookFunc{
+		replaceAll("${WEBHOOK_IMAGE}", os.Getenv(envSharedResourceDriverWebhookImage)),
+		replaceLogLevel,
+	}
+	deploymentHooks := []deploymentcontroller.DeploymentHookFunc{
 		csidrivercontrollerservicecontroller.WithControlPlaneTopologyHook(configInformer),
-		csidrivercontrollerservicecontroller.WithReplicasHook(nodeLister),
 		csidrivercontrollerservicecontroller.WithSecretHashAnnotationHook(
-			defaultNamespace,
+			namespace,
 			webhookSecretName,
 			secretInformer,
 		),
-	)
+		hooks.WithReservedNamesDeploymentHook(configMapInformer.Lister().ConfigMaps(config.DefaultNamespace)),
+		hooks.WithResourcesDeploymentHook(configMapInformer.Lister().ConfigMaps(config.DefaultNamespace)),
+	}
+	if len(hostedControlPlaneNamespace) > 0 {
+		// the nodes of the guest cluster say nothing about the management cluster the webhook runs in, so the
+		// single replica of the manifest is kept
+		manifestHooks = append(manifestHooks, replaceAll("namespace: "+config.DefaultNamespace, "namespace: "+hostedControlPlaneNamespace))
+		deploymentHooks = append(deploymentHooks, hooks.WithHostedControlPlaneDeploymentHook(hostedControlPlaneNamespace))
+	} else {
+		deploymentHooks = append(deploymentHooks, csidrivercontrollerservicecontroller.WithReplicasHook(nodeLister))
+	}
+
+	deploymentInformer := controlPlaneInformers.InformersFor(namespace).Apps().V1().Deployments()
+	informers := []factory.Informer{
+		secretInformer.Informer(),
+		configMapInformer.Informer(),
+		configInformer.Config().V1().Infrastructures().Informer(),
+	}
+	// Only the sync of the library-go controller is used, so that its duration and errors can be measured. The
+	// controller running it reports the <name>Degraded condition in its place.
+	deploymentController, err := deploymentcontroller.NewDeploymentControllerBuilder(
+		controllerName,
+		assets.MustAsset("webhook/deployment.yaml"),
+		recorder,
+		operatorClient,
+		kubeClient,
+		deploymentInformer,
+	).WithConditions(
+		operatorv1.OperatorStatusTypeAvailable,
+		operatorv1.OperatorStatusTypeProgressing,
+	).WithExtraInformers(
+		informers...,
+	).WithManifestHooks(
+		manifestHooks...,
+	).WithDeploymentHooks(
+		deploymentHooks...,
+	).ToController()
+	if err != nil {
+		return nil, err
+	}
+
+	return factory.New().WithInformers(
+		append(informers, operatorClient.Informer(), deploymentInformer.Informer())...,
+	).WithSync(
+		metrics.InstrumentSync(controllerName, deploymentController.Sync),
+	).WithSyncDegradedOnError(
+		operatorClient,
+	).ResyncEvery(
+		time.Minute,
+	).ToController(
+		controllerName,
+		metrics.InstrumentRecorder(controllerName, recorder).WithComponentSuffix(strings.ToLower(controllerName)+"-deployment-controller-"),
+	), nil
 }
 
 func replaceAll(old, new string) deploymentcontroller.ManifestHookFunc {
@@ -60,3 +119,10 @@ func replaceAll(old, new string) deploymentcontroller.ManifestHookFunc {
 		return bytes.ReplaceAll(manifest, []byte(old), []byte(new)), nil
 	}
 }
+
+// replaceLogLevel replaces ${LOG_LEVEL} with the verbosity of the log level of the ClusterCSIDriver, as library-go
+// does for the driver DaemonSet.
+func replaceLogLevel(spec *operatorv1.OperatorSpec, manifest []byte) ([]byte, error) {
+	verbosity := strconv.Itoa(loglevel.LogLevelToVerbosity(spec.LogLevel))
+	return bytes.ReplaceAll(manifest, []byte("${LOG_LEVEL}"), []byte(verbosity)), nil
+}
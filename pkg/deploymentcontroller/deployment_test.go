@@ -0,0 +1,38 @@
+package deploymentcontroller
+
+import (
+	"testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+)
+
+func TestReplaceLogLevel(t *testing.T) {
+	for _, test := range []struct {
+		logLevel operatorv1.LogLevel
+		expected string
+	}{
+		{logLevel: "", expected: "--v=2"},
+		{logLevel: operatorv1.Normal, expected: "--v=2"},
+		{logLevel: operatorv1.Debug, expected: "--v=4"},
+		{logLevel: operatorv1.Trace, expected: "--v=6"},
+		{logLevel: operatorv1.TraceAll, expected: "--v=8"},
+	} {
+		manifest, err := replaceLogLevel(&operatorv1.OperatorSpec{LogLevel: test.logLevel}, assets.MustAsset("webhook/deployment.yaml"))
+		if err != nil {
+			t.Fatalf("testcase %q: unexpected error %v", test.logLevel, err)
+		}
+		deployment := resourceread.ReadDeploymentV1OrDie(manifest)
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			found := false
+			for _, arg := range container.Args {
+				found = found || arg == test.expected
+			}
+			if !found {
+				t.Errorf("testcase %q: expected %s on container %s, got %v", test.logLevel, test.expected, container.Name, container.Args)
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package entitlementcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	rbacv1informers "k8s.io/client-go/informers/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	shareclientv1alpha1 "github.com/openshift/client-go/sharedresource/clientset/versioned"
+	shareinformersv1alpha1 "github.com/openshift/client-go/sharedresource/informers/externalversions/sharedresource/v1alpha1"
+	sharelistersv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+const (
+	controllerName = "SharedResourceEntitlementController"
+
+	// EntitlementNamespace and EntitlementSecretName identify the Secret holding the cluster entitlement
+	// certificates, published by the Insights operator.
+	EntitlementNamespace  = "openshift-config-managed"
+	EntitlementSecretName = "etc-pki-entitlement"
+	// EntitlementShareName is the SharedSecret reserved for the entitlement Secret.
+	EntitlementShareName = "openshift-etc-pki-entitlement"
+	// entitlementRBACName names both the ClusterRole granting use of the share and its ClusterRoleBinding
+	entitlementRBACName = "shared-resource-etc-pki-entitlement"
+
+	// EntitlementDegradedConditionType is reported on the ClusterCSIDriver when the entitlement share or its
+	// RBAC cannot be maintained.
+	EntitlementDegradedConditionType = "EntitlementShareDegraded"
+
+	resyncInterval = 10 * time.Minute
+)
+
+// entitlementController provisions the openshift-etc-pki-entitlement SharedSecret while the entitlement
+// Secret exists, and deletes it once the Secret is gone. It also maintains a ClusterRole granting use of the
+// share and binds it to the entitlementSubjects of the operator configuration; the ClusterRoleBinding is
+// removed when no subjects are configured.
+type entitlementController struct {
+	kubeClient      kubernetes.Interface
+	shareClient     shareclientv1alpha1.Interface
+	operatorClient  v1helpers.OperatorClient
+	secretLister    corev1listers.SecretNamespaceLister
+	configMapLister corev1listers.ConfigMapNamespaceLister
+	shareLister     sharelistersv1alpha1.SharedSecretLister
+	bindingLister   rbacv1listers.ClusterRoleBindingLister
+}
+
+func NewEntitlementController(kubeClient kubernetes.Interface,
+	shareClient shareclientv1alpha1.Interface,
+	operatorClient v1helpers.OperatorClient,
+	secretInformer corev1informers.SecretInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	shareInformer shareinformersv1alpha1.SharedSecretInformer,
+	clusterRoleInformer rbacv1informers.ClusterRoleInformer,
+	clusterRoleBindingInformer rbacv1informers.ClusterRoleBindingInformer,
+	recorder events.Recorder) factory.Controller {
+
+	c := &entitlementController{
+		kubeClient:      kubeClient,
+		shareClient:     shareClient,
+		operatorClient:  operatorClient,
+		secretLister:    secretInformer.Lister().Secrets(EntitlementNamespace),
+		configMapLister: configMapInformer.Lister().ConfigMaps(config.DefaultNamespace),
+		shareLister:     shareInformer.Lister(),
+		bindingLister:   clusterRoleBindingInformer.Lister(),
+	}
+	return factory.New().WithFilteredEventsInformers(
+		factory.NamesFilter(EntitlementSecretName),
+		secretInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(config.OperatorConfigMapName),
+		configMapInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(EntitlementShareName),
+		shareInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(entitlementRBACName),
+		clusterRoleInformer.Informer(),
+		clusterRoleBindingInformer.Informer(),
+	).WithSync(
+		c.sync,
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		recorder.WithComponentSuffix("shared-resource-entitlement-controller"),
+	)
+}
+
+func (c *entitlementController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	var errs []error
+	// the share does not depend on the operator configuration, so it is kept in sync with the Secret regardless
+	if err := c.syncShare(ctx, syncContext.Recorder()); err != nil {
+		errs = append(errs, err)
+	}
+	opConfig, configErr := config.GetOperatorConfig(c.configMapLister)
+	if configErr == nil {
+		if err := c.syncRBAC(ctx, syncContext.Recorder(), opConfig.EntitlementSubjects); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	syncErr := v1helpers.NewMultiLineAggregate(errs)
+	reason := ""
+	conditionErr := syncErr
+	switch {
+	case configErr != nil:
+		// the current bindings are kept until the operator configuration is fixed, so it is reported rather than retried
+		reason = "InvalidConfiguration"
+		conditionErr = v1helpers.NewMultiLineAggregate(append([]error{configErr}, errs...))
+	case syncErr != nil:
+		reason = "SyncFailed"
+	}
+	if err := c.updateCondition(ctx, reason, conditionErr); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+func (c *entitlementController) syncShare(ctx context.Context, recorder events.Recorder) error {
+	_, err := c.secretLister.Get(EntitlementSecretName)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("unexpected error determining if Secret %s/%s exists: %s", EntitlementNamespace, EntitlementSecretName, err)
+	}
+	secretExists := err == nil
+
+	existing, err := c.shareLister.Get(EntitlementShareName)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("unexpected error determining if SharedSecret %q exists: %s", EntitlementShareName, err)
+	}
+	shareExists := err == nil
+
+	switch {
+	case !secretExists && !shareExists:
+		return nil
+	case !secretExists:
+		err := c.shareClient.SharedresourceV1alpha1().SharedSecrets().Delete(ctx, EntitlementShareName, metav1.DeleteOptions{})
+		if kerrors.IsNotFound(err) {
+			// already deleted since the lister last saw it
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to delete SharedSecret %q: %s", EntitlementShareName, err)
+		}
+		recorder.Eventf("SharedSecretDeleted", "Deleted SharedSecret %s as Secret %s/%s no longer exists", EntitlementShareName, EntitlementNamespace, EntitlementSecretName)
+		return nil
+	}
+
+	required := requiredShare()
+	if !shareExists {
+		if _, err := c.shareClient.SharedresourceV1alpha1().SharedSecrets().Create(ctx, required, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("unable to create SharedSecret %q: %s", EntitlementShareName, err)
+		}
+		recorder.Eventf("SharedSecretCreated", "Created SharedSecret %s for Secret %s/%s", EntitlementShareName, EntitlementNamespace, EntitlementSecretName)
+		return nil
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, required.Spec) {
+		return nil
+	}
+	updated := existing.DeepCopy()
+	updated.Spec = required.Spec
+	if _, err := c.shareClient.SharedresourceV1alpha1().SharedSecrets().Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update SharedSecret %q: %s", EntitlementShareName, err)
+	}
+	recorder.Eventf("SharedSecretUpdated", "Updated SharedSecret %s to reference Secret %s/%s", EntitlementShareName, EntitlementNamespace, EntitlementSecretName)
+	return nil
+}
+
+func (c *entitlementController) syncRBAC(ctx context.Context, recorder events.Recorder, subjects []rbacv1.Subject) error {
+	if _, _, err := resourceapply.ApplyClusterRole(ctx, c.kubeClient.RbacV1(), recorder, requiredClusterRole()); err != nil {
+		return fmt.Errorf("error applying ClusterRole %q: %w", entitlementRBACName, err)
+	}
+
+	if len(subjects) == 0 {
+		_, err := c.bindingLister.Get(entitlementRBACName)
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unexpected error determining if ClusterRoleBinding %q exists: %s", entitlementRBACName, err)
+		}
+		err = c.kubeClient.RbacV1().ClusterRoleBindings().Delete(ctx, entitlementRBACName, metav1.DeleteOptions{})
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to delete ClusterRoleBinding %q: %s", entitlementRBACName, err)
+		}
+		recorder.Eventf("ClusterRoleBindingDeleted", "Deleted ClusterRoleBinding %s as no entitlement subjects are configured", entitlementRBACName)
+		return nil
+	}
+	if _, _, err := resourceapply.ApplyClusterRoleBinding(ctx, c.kubeClient.RbacV1(), recorder, requiredClusterRoleBinding(subjects)); err != nil {
+		return fmt.Errorf("error applying ClusterRoleBinding %q: %w", entitlementRBACName, err)
+	}
+	return nil
+}
+
+func (c *entitlementController) updateCondition(ctx context.Context, reason string, err error) error {
+	condition := operatorv1.OperatorCondition{
+		Type:   EntitlementDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if err != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = err.Error()
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return updateErr
+}
+
+func requiredShare() *sharev1alpha1.SharedSecret {
+	return &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: EntitlementShareName,
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      EntitlementSecretName,
+				Namespace: EntitlementNamespace,
+			},
+		},
+	}
+}
+
+func requiredClusterRole() *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: entitlementRBACName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups:     []string{sharev1alpha1.GroupName},
+				Resources:     []string{"sharedsecrets"},
+				ResourceNames: []string{EntitlementShareName},
+				Verbs:         []string{"use"},
+			},
+		},
+	}
+}
+
+func requiredClusterRoleBinding(subjects []rbacv1.Subject) *rbacv1.ClusterRoleBinding {
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: entitlementRBACName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     entitlementRBACName,
+		},
+	}
+	for _, subject := range subjects {
+		// users and groups need the API group, which is easy to leave out in the configuration
+		if subject.Kind != rbacv1.ServiceAccountKind && len(subject.APIGroup) == 0 {
+			subject.APIGroup = rbacv1.GroupName
+		}
+		binding.Subjects = append(binding.Subjects, subject)
+	}
+	return binding
+}
@@ -0,0 +1,207 @@
+package entitlementcontroller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	sharefake "github.com/openshift/client-go/sharedresource/clientset/versioned/fake"
+	sharelistersv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func entitlementSecret() *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: EntitlementSecretName, Namespace: EntitlementNamespace}}
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		secret         *corev1.Secret
+		share          *sharev1alpha1.SharedSecret
+		staleShare     bool
+		binding        *rbacv1.ClusterRoleBinding
+		operatorConfig string
+		expectShare    bool
+		expectSubjects []rbacv1.Subject
+		expectDegraded bool
+		expectDeleted  bool
+	}{
+		{
+			name:        "share is created for the entitlement Secret",
+			secret:      entitlementSecret(),
+			expectShare: true,
+		},
+		{
+			name:          "share is deleted once the Secret is gone",
+			share:         requiredShare(),
+			expectDeleted: true,
+		},
+		{
+			name:       "share already deleted is not reported",
+			share:      requiredShare(),
+			staleShare: true,
+		},
+		{
+			name:        "share pointing elsewhere is restored",
+			secret:      entitlementSecret(),
+			share:       &sharev1alpha1.SharedSecret{ObjectMeta: metav1.ObjectMeta{Name: EntitlementShareName}},
+			expectShare: true,
+		},
+		{
+			name:   "configured subjects are bound",
+			secret: entitlementSecret(),
+			operatorConfig: `entitlementSubjects:
+- kind: ServiceAccount
+  name: builder
+  namespace: my-builds
+- kind: Group
+  name: entitled-builders
+`,
+			expectShare: true,
+			expectSubjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "builder", Namespace: "my-builds"},
+				{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "entitled-builders"},
+			},
+		},
+		{
+			name:        "binding is removed with the last subject",
+			secret:      entitlementSecret(),
+			binding:     requiredClusterRoleBinding([]rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "someone"}}),
+			expectShare: true,
+		},
+		{
+			name:   "invalid subjects are reported and the share still created",
+			secret: entitlementSecret(),
+			binding: requiredClusterRoleBinding([]rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "builder", Namespace: "my-builds"},
+			}),
+			operatorConfig: `entitlementSubjects:
+- kind: ServiceAccount
+  name: builder
+`,
+			expectShare: true,
+			expectSubjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "builder", Namespace: "my-builds"},
+			},
+			expectDegraded: true,
+		},
+		{
+			name:           "share is deleted while the operator configuration is invalid",
+			share:          requiredShare(),
+			operatorConfig: "entitlementSubjects: [{kind: ServiceAccount, name: builder}]\n",
+			expectDeleted:  true,
+			expectDegraded: true,
+		},
+	} {
+		kubeObjects := []runtime.Object{}
+		secretIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.secret != nil {
+			secretIndexer.Add(test.secret)
+			kubeObjects = append(kubeObjects, test.secret)
+		}
+		bindingIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.binding != nil {
+			bindingIndexer.Add(test.binding)
+			kubeObjects = append(kubeObjects, test.binding)
+		}
+		shareObjects := []runtime.Object{}
+		shareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.share != nil {
+			shareIndexer.Add(test.share)
+			if !test.staleShare {
+				shareObjects = append(shareObjects, test.share)
+			}
+		}
+		cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if len(test.operatorConfig) > 0 {
+			cmIndexer.Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: config.OperatorConfigMapName, Namespace: config.DefaultNamespace},
+				Data:       map[string]string{config.ConfigKey: test.operatorConfig},
+			})
+		}
+
+		kubeClient := fake.NewSimpleClientset(kubeObjects...)
+		shareClient := sharefake.NewSimpleClientset(shareObjects...)
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &entitlementController{
+			kubeClient:      kubeClient,
+			shareClient:     shareClient,
+			operatorClient:  operatorClient,
+			secretLister:    corev1listers.NewSecretLister(secretIndexer).Secrets(EntitlementNamespace),
+			configMapLister: corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+			shareLister:     sharelistersv1alpha1.NewSharedSecretLister(shareIndexer),
+			bindingLister:   rbacv1listers.NewClusterRoleBindingLister(bindingIndexer),
+		}
+
+		recorder := events.NewInMemoryRecorder(controllerName)
+		err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, recorder))
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		deleted := false
+		for _, event := range recorder.Events() {
+			deleted = deleted || event.Reason == "SharedSecretDeleted"
+		}
+		if deleted != test.expectDeleted {
+			t.Errorf("testcase %s: expected a SharedSecretDeleted event %v, got %v", test.name, test.expectDeleted, deleted)
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		degraded := v1helpers.IsOperatorConditionTrue(status.Conditions, EntitlementDegradedConditionType)
+		if degraded != test.expectDegraded {
+			t.Errorf("testcase %s: expected condition %s to be %v, got %v", test.name, EntitlementDegradedConditionType, test.expectDegraded, status.Conditions)
+		}
+		if condition := v1helpers.FindOperatorCondition(status.Conditions, EntitlementDegradedConditionType); test.expectDegraded && condition.Reason != "InvalidConfiguration" {
+			t.Errorf("testcase %s: expected reason InvalidConfiguration, got %v", test.name, condition)
+		}
+
+		share, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), EntitlementShareName, metav1.GetOptions{})
+		switch {
+		case test.expectShare && err != nil:
+			t.Errorf("testcase %s: expected the share to exist, got %v", test.name, err)
+		case test.expectShare && share.Spec != requiredShare().Spec:
+			t.Errorf("testcase %s: unexpected share spec %#v", test.name, share.Spec)
+		case !test.expectShare && !kerrors.IsNotFound(err):
+			t.Errorf("testcase %s: expected the share to be deleted, got %v", test.name, err)
+		}
+
+		// the RBAC is left untouched while the operator configuration is invalid
+		if _, err := kubeClient.RbacV1().ClusterRoles().Get(context.TODO(), entitlementRBACName, metav1.GetOptions{}); err != nil && !test.expectDegraded {
+			t.Errorf("testcase %s: expected the ClusterRole to exist, got %v", test.name, err)
+		}
+		binding, err := kubeClient.RbacV1().ClusterRoleBindings().Get(context.TODO(), entitlementRBACName, metav1.GetOptions{})
+		if len(test.expectSubjects) == 0 {
+			if !kerrors.IsNotFound(err) {
+				t.Errorf("testcase %s: expected no ClusterRoleBinding, got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("testcase %s: expected the ClusterRoleBinding to exist, got %v", test.name, err)
+		}
+		if len(binding.Subjects) != len(test.expectSubjects) {
+			t.Fatalf("testcase %s: expected subjects %v, got %v", test.name, test.expectSubjects, binding.Subjects)
+		}
+		for i := range binding.Subjects {
+			if binding.Subjects[i] != test.expectSubjects[i] {
+				t.Errorf("testcase %s: expected subjects %v, got %v", test.name, test.expectSubjects, binding.Subjects)
+			}
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package grantcontroller
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	rbacv1informers "k8s.io/client-go/informers/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/util/retry"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	shareclientv1alpha1 "github.com/openshift/client-go/sharedresource/clientset/versioned"
+	shareinformersv1alpha1 "github.com/openshift/client-go/sharedresource/informers/externalversions/sharedresource/v1alpha1"
+	sharelistersv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+const (
+	controllerName = "SharedResourceGrantController"
+
+	// GrantLabel marks the Roles and RoleBindings generated from grants. Only objects carrying it are garbage
+	// collected, and the informers handed to NewGrantController are expected to be filtered on it.
+	GrantLabel = "sharedresource.openshift.io/grant"
+	// grantShareAnnotation records, as <resource>/<name>, the share a generated object grants use of
+	grantShareAnnotation = "sharedresource.openshift.io/grant-share"
+
+	// GrantedConditionType is set on every share named in the shareGrants of the operator configuration.
+	GrantedConditionType = "AccessGranted"
+	// GrantsDegradedConditionType is reported on the ClusterCSIDriver when generated RBAC cannot be maintained.
+	GrantsDegradedConditionType = "ShareGrantsDegraded"
+
+	// the namespaces of a grant are listed in the share condition up to this count
+	maxListedNamespaces = 10
+
+	resyncInterval = 10 * time.Minute
+)
+
+// share is the part of a SharedSecret or SharedConfigMap the grant controller works on.
+type share struct {
+	// resource is the plural resource name, sharedsecrets or sharedconfigmaps
+	resource   string
+	name       string
+	conditions []metav1.Condition
+	// updateConditions applies mutate to the current conditions of the share, retrying on conflicts, so that
+	// the conditions other controllers set on the share are kept
+	updateConditions func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error
+}
+
+func (s *share) key() string {
+	return s.resource + "/" + s.name
+}
+
+// grantController turns the shareGrants of the operator configuration into a Role granting use of the share,
+// and a RoleBinding to the listed service accounts, in every target namespace of each existing share. Grants
+// are only read from the administrator owned operator configuration, as the operator creates RBAC that the
+// editors of a share may not be allowed to create themselves. Generated objects no longer backed by a grant are
+// deleted, except while the operator configuration is invalid, when every grant keeps its current access. The
+// outcome is recorded on each share in the AccessGranted condition.
+type grantController struct {
+	kubeClient            kubernetes.Interface
+	shareClient           shareclientv1alpha1.Interface
+	operatorClient        v1helpers.OperatorClient
+	configMapLister       corev1listers.ConfigMapNamespaceLister
+	namespaceLister       corev1listers.NamespaceLister
+	sharedSecretLister    sharelistersv1alpha1.SharedSecretLister
+	sharedConfigMapLister sharelistersv1alpha1.SharedConfigMapLister
+	roleLister            rbacv1listers.RoleLister
+	roleBindingLister     rbacv1listers.RoleBindingLister
+}
+
+func NewGrantController(kubeClient kubernetes.Interface,
+	shareClient shareclientv1alpha1.Interface,
+	operatorClient v1helpers.OperatorClient,
+	configMapInformer corev1informers.ConfigMapInformer,
+	namespaceInformer corev1informers.NamespaceInformer,
+	sharedSecretInformer shareinformersv1alpha1.SharedSecretInformer,
+	sharedConfigMapInformer shareinformersv1alpha1.SharedConfigMapInformer,
+	grantRoleInformer rbacv1informers.RoleInformer,
+	grantRoleBindingInformer rbacv1informers.RoleBindingInformer,
+	recorder events.Recorder) factory.Controller {
+
+	c := &grantController{
+		kubeClient:            kubeClient,
+		shareClient:           shareClient,
+		operatorClient:        operatorClient,
+		configMapLister:       configMapInformer.Lister().ConfigMaps(config.DefaultNamespace),
+		namespaceLister:       namespaceInformer.Lister(),
+		sharedSecretLister:    sharedSecretInformer.Lister(),
+		sharedConfigMapLister: sharedConfigMapInformer.Lister(),
+		roleLister:            grantRoleInformer.Lister(),
+		roleBindingLister:     grantRoleBindingInformer.Lister(),
+	}
+	return factory.New().WithFilteredEventsInformers(
+		factory.NamesFilter(config.OperatorConfigMapName),
+		configMapInformer.Informer(),
+	).WithFilteredEventsInformers(
+		c.isGrantNamespace,
+		namespaceInformer.Informer(),
+	).WithFilteredEventsInformers(
+		isGenerated,
+		grantRoleInformer.Informer(),
+		grantRoleBindingInformer.Informer(),
+	).WithInformers(
+		sharedSecretInformer.Informer(),
+		sharedConfigMapInformer.Informer(),
+	).WithSync(
+		c.sync,
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		recorder.WithComponentSuffix("shared-resource-grant-controller"),
+	)
+}
+
+// isGrantNamespace accepts the namespaces a grant of the operator configuration names or selects. Namespaces
+// that stop being selected still pass, as the informer hands over their previous state. While the operator
+// configuration is invalid the current access is kept, so no namespace event needs a sync.
+func (c *grantController) isGrantNamespace(obj interface{}) bool {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		// tombstones
+		return true
+	}
+	opConfig, err := config.GetOperatorConfig(c.configMapLister)
+	if err != nil {
+		return false
+	}
+	for _, grant := range opConfig.ShareGrants {
+		for _, name := range grant.Namespaces {
+			if name == ns.Name {
+				return true
+			}
+		}
+		if grant.NamespaceSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(grant.NamespaceSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGenerated accepts the Roles and RoleBindings carrying GrantLabel, in case the informers handed to
+// NewGrantController are not filtered on it.
+func isGenerated(obj interface{}) bool {
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		// tombstones
+		return true
+	}
+	return o.GetLabels()[GrantLabel] == "true"
+}
+
+func (c *grantController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	opConfig, err := config.GetOperatorConfig(c.configMapLister)
+	if err != nil {
+		// the current access is kept until the operator configuration is fixed
+		return c.updateCondition(ctx, "InvalidConfiguration", err)
+	}
+	grants := map[string]*config.ShareGrant{}
+	for i := range opConfig.ShareGrants {
+		grants[opConfig.ShareGrants[i].ShareKey()] = &opConfig.ShareGrants[i]
+	}
+
+	shares, err := c.listShares()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	desired := sets.New[string]()
+	// shares whose generated objects must not be garbage collected in this sync
+	keep := sets.New[string]()
+	for _, s := range shares {
+		grant, ok := grants[s.key()]
+		if !ok {
+			if err := c.setCondition(ctx, s, nil); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		serviceAccounts := sets.List(sets.New(grant.ServiceAccounts...))
+
+		namespaces, err := c.targetNamespaces(grant)
+		if err != nil {
+			keep.Insert(s.key())
+			errs = append(errs, err)
+			continue
+		}
+		var applyErrs []error
+		for _, ns := range namespaces {
+			role := requiredRole(s, ns)
+			binding := requiredRoleBinding(s, ns, serviceAccounts)
+			desired.Insert(ns + "/" + role.Name)
+			if err := c.applyRole(ctx, syncContext.Recorder(), role); err != nil {
+				applyErrs = append(applyErrs, err)
+			}
+			if err := c.applyRoleBinding(ctx, syncContext.Recorder(), binding); err != nil {
+				applyErrs = append(applyErrs, err)
+			}
+		}
+		condition := &metav1.Condition{
+			Type:    GrantedConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Granted",
+			Message: grantedMessage(serviceAccounts, namespaces),
+		}
+		if len(applyErrs) > 0 {
+			errs = append(errs, applyErrs...)
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "ApplyFailed"
+			condition.Message = v1helpers.NewMultiLineAggregate(applyErrs).Error()
+		}
+		if err := c.setCondition(ctx, s, condition); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.garbageCollect(ctx, syncContext.Recorder(), desired, keep); err != nil {
+		errs = append(errs, err)
+	}
+
+	syncErr := v1helpers.NewMultiLineAggregate(errs)
+	if err := c.updateCondition(ctx, "SyncFailed", syncErr); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+func (c *grantController) listShares() ([]*share, error) {
+	sharedSecrets, err := c.sharedSecretLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sharedConfigMaps, err := c.sharedConfigMapLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*share, 0, len(sharedSecrets)+len(sharedConfigMaps))
+	for _, s := range sharedSecrets {
+		s := s
+		shares = append(shares, &share{
+			resource:   "sharedsecrets",
+			name:       s.Name,
+			conditions: s.Status.Conditions,
+			updateConditions: func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					current, err := c.shareClient.SharedresourceV1alpha1().SharedSecrets().Get(ctx, s.Name, metav1.GetOptions{})
+					if kerrors.IsNotFound(err) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if !mutateConditions(&current.Status.Conditions, mutate) {
+						return nil
+					}
+					_, err = c.shareClient.SharedresourceV1alpha1().SharedSecrets().UpdateStatus(ctx, current, metav1.UpdateOptions{})
+					return err
+				})
+			},
+		})
+	}
+	for _, s := range sharedConfigMaps {
+		s := s
+		shares = append(shares, &share{
+			resource:   "sharedconfigmaps",
+			name:       s.Name,
+			conditions: s.Status.Conditions,
+			updateConditions: func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					current, err := c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().Get(ctx, s.Name, metav1.GetOptions{})
+					if kerrors.IsNotFound(err) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if !mutateConditions(&current.Status.Conditions, mutate) {
+						return nil
+					}
+					_, err = c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().UpdateStatus(ctx, current, metav1.UpdateOptions{})
+					return err
+				})
+			},
+		})
+	}
+	return shares, nil
+}
+
+// targetNamespaces returns the sorted names of the existing namespaces the grant applies to.
+func (c *grantController) targetNamespaces(grant *config.ShareGrant) ([]string, error) {
+	namespaces := sets.New[string]()
+	for _, name := range grant.Namespaces {
+		_, err := c.namespaceLister.Get(name)
+		if kerrors.IsNotFound(err) {
+			// granted as soon as the informer sees it created
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unexpected error determining if namespace %q exists: %s", name, err)
+		}
+		namespaces.Insert(name)
+	}
+	if grant.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(grant.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+		selected, err := c.namespaceLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range selected {
+			namespaces.Insert(ns.Name)
+		}
+	}
+	return sets.List(namespaces), nil
+}
+
+func (c *grantController) applyRole(ctx context.Context, recorder events.Recorder, required *rbacv1.Role) error {
+	existing, err := c.roleLister.Roles(required.Namespace).Get(required.Name)
+	if err == nil && equality.Semantic.DeepEqual(existing.Rules, required.Rules) && hasMetadata(existing.ObjectMeta, required.ObjectMeta) {
+		return nil
+	}
+	if _, _, err := resourceapply.ApplyRole(ctx, c.kubeClient.RbacV1(), recorder, required); err != nil {
+		return fmt.Errorf("error applying Role %s/%s: %w", required.Namespace, required.Name, err)
+	}
+	return nil
+}
+
+func (c *grantController) applyRoleBinding(ctx context.Context, recorder events.Recorder, required *rbacv1.RoleBinding) error {
+	existing, err := c.roleBindingLister.RoleBindings(required.Namespace).Get(required.Name)
+	if err == nil && equality.Semantic.DeepEqual(existing.RoleRef, required.RoleRef) &&
+		equality.Semantic.DeepEqual(existing.Subjects, required.Subjects) && hasMetadata(existing.ObjectMeta, required.ObjectMeta) {
+		return nil
+	}
+	if _, _, err := resourceapply.ApplyRoleBinding(ctx, c.kubeClient.RbacV1(), recorder, required); err != nil {
+		return fmt.Errorf("error applying RoleBinding %s/%s: %w", required.Namespace, required.Name, err)
+	}
+	return nil
+}
+
+// garbageCollect deletes the generated Roles and RoleBindings that are neither desired nor kept.
+func (c *grantController) garbageCollect(ctx context.Context, recorder events.Recorder, desired, keep sets.Set[string]) error {
+	selector := labels.SelectorFromSet(labels.Set{GrantLabel: "true"})
+	roles, err := c.roleLister.List(selector)
+	if err != nil {
+		return err
+	}
+	bindings, err := c.roleBindingLister.List(selector)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, role := range roles {
+		if desired.Has(role.Namespace+"/"+role.Name) || keep.Has(role.Annotations[grantShareAnnotation]) {
+			continue
+		}
+		err := c.kubeClient.RbacV1().Roles(role.Namespace).Delete(ctx, role.Name, metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("unable to delete Role %s/%s: %s", role.Namespace, role.Name, err))
+			continue
+		}
+		recorder.Eventf("GrantRevoked", "Deleted Role %s/%s granting use of %s", role.Namespace, role.Name, role.Annotations[grantShareAnnotation])
+	}
+	for _, binding := range bindings {
+		if desired.Has(binding.Namespace+"/"+binding.Name) || keep.Has(binding.Annotations[grantShareAnnotation]) {
+			continue
+		}
+		err := c.kubeClient.RbacV1().RoleBindings(binding.Namespace).Delete(ctx, binding.Name, metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("unable to delete RoleBinding %s/%s: %s", binding.Namespace, binding.Name, err))
+			continue
+		}
+		recorder.Eventf("GrantRevoked", "Deleted RoleBinding %s/%s granting use of %s", binding.Namespace, binding.Name, binding.Annotations[grantShareAnnotation])
+	}
+	return v1helpers.NewMultiLineAggregate(errs)
+}
+
+// setCondition sets, or with a nil condition removes, the AccessGranted condition of s. The share status
+// is only updated when the conditions change.
+func (c *grantController) setCondition(ctx context.Context, s *share, condition *metav1.Condition) error {
+	mutate := func(conditions *[]metav1.Condition) {
+		if condition == nil {
+			meta.RemoveStatusCondition(conditions, GrantedConditionType)
+		} else {
+			meta.SetStatusCondition(conditions, *condition)
+		}
+	}
+	conditions := append([]metav1.Condition(nil), s.conditions...)
+	if !mutateConditions(&conditions, mutate) {
+		return nil
+	}
+	if err := s.updateConditions(ctx, mutate); err != nil {
+		return fmt.Errorf("unable to update the status of %s: %s", s.key(), err)
+	}
+	return nil
+}
+
+// mutateConditions applies mutate to conditions and returns whether they changed.
+func mutateConditions(conditions *[]metav1.Condition, mutate func(conditions *[]metav1.Condition)) bool {
+	original := append([]metav1.Condition(nil), *conditions...)
+	mutate(conditions)
+	return !equality.Semantic.DeepEqual(original, *conditions)
+}
+
+func (c *grantController) updateCondition(ctx context.Context, reason string, err error) error {
+	condition := operatorv1.OperatorCondition{
+		Type:   GrantsDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if err != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = err.Error()
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return updateErr
+}
+
+func grantedMessage(serviceAccounts, namespaces []string) string {
+	switch {
+	case len(namespaces) == 0:
+		return "no target namespace exists"
+	case len(namespaces) > maxListedNamespaces:
+		return fmt.Sprintf("use granted to service accounts %s in %d namespaces", strings.Join(serviceAccounts, ", "), len(namespaces))
+	default:
+		return fmt.Sprintf("use granted to service accounts %s in namespaces %s", strings.Join(serviceAccounts, ", "), strings.Join(namespaces, ", "))
+	}
+}
+
+// grantObjectName returns the name of the Role and RoleBinding generated for s, shortened with a hash of the
+// share name when it would exceed the maximum length.
+func grantObjectName(s *share) string {
+	// sharedsecrets -> sharedsecret
+	name := fmt.Sprintf("shared-resource-%s-%s", strings.TrimSuffix(s.resource, "s"), s.name)
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(s.name)))[:8]
+	return name[:validation.DNS1123SubdomainMaxLength-len(hash)-1] + "-" + hash
+}
+
+func grantObjectMeta(s *share, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        grantObjectName(s),
+		Namespace:   namespace,
+		Labels:      map[string]string{GrantLabel: "true"},
+		Annotations: map[string]string{grantShareAnnotation: s.key()},
+	}
+}
+
+func requiredRole(s *share, namespace string) *rbacv1.Role {
+	return &rbacv1.Role{
+		ObjectMeta: grantObjectMeta(s, namespace),
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups:     []string{sharev1alpha1.GroupName},
+				Resources:     []string{s.resource},
+				ResourceNames: []string{s.name},
+				Verbs:         []string{"use"},
+			},
+		},
+	}
+}
+
+func requiredRoleBinding(s *share, namespace string, serviceAccounts []string) *rbacv1.RoleBinding {
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: grantObjectMeta(s, namespace),
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     grantObjectName(s),
+		},
+	}
+	for _, sa := range serviceAccounts {
+		binding.Subjects = append(binding.Subjects, rbacv1.Subject{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      sa,
+			Namespace: namespace,
+		})
+	}
+	return binding
+}
+
+// hasMetadata reports whether existing carries the labels and annotations of required.
+func hasMetadata(existing, required metav1.ObjectMeta) bool {
+	for k, v := range required.Labels {
+		if existing.Labels[k] != v {
+			return false
+		}
+	}
+	for k, v := range required.Annotations {
+		if existing.Annotations[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,276 @@
+package grantcontroller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	sharefake "github.com/openshift/client-go/sharedresource/clientset/versioned/fake"
+	sharelistersv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func sharedSecret(name string, conditions ...metav1.Condition) *sharev1alpha1.SharedSecret {
+	return &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{Name: "secret", Namespace: "source"},
+		},
+		Status: sharev1alpha1.SharedSecretStatus{Conditions: conditions},
+	}
+}
+
+func operatorConfigMap(data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: config.OperatorConfigMapName, Namespace: config.DefaultNamespace},
+		Data:       map[string]string{config.ConfigKey: data},
+	}
+}
+
+func generatedRole(shareName, ns string) *rbacv1.Role {
+	return requiredRole(&share{resource: "sharedsecrets", name: shareName}, ns)
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		share           *sharev1alpha1.SharedSecret
+		operatorConfig  string
+		roles           []*rbacv1.Role
+		expectGranted   []string
+		expectKept      []string
+		expectDeleted   []string
+		expectCondition metav1.ConditionStatus
+		expectReason    string
+		expectDegraded  string
+	}{
+		{
+			name:  "named and selected namespaces are granted, missing ones skipped",
+			share: sharedSecret("my-share"),
+			operatorConfig: `shareGrants:
+- sharedSecret: my-share
+  namespaces: [team-a, not-created-yet]
+  namespaceSelector: {matchLabels: {team: builds}}
+  serviceAccounts: [builder]
+`,
+			expectGranted:   []string{"team-a", "team-b"},
+			expectCondition: metav1.ConditionTrue,
+			expectReason:    "Granted",
+		},
+		{
+			name:            "namespaces no longer granted are cleaned up",
+			share:           sharedSecret("my-share"),
+			operatorConfig:  "shareGrants:\n- {sharedSecret: my-share, namespaces: [team-a], serviceAccounts: [builder]}\n",
+			roles:           []*rbacv1.Role{generatedRole("my-share", "team-a"), generatedRole("my-share", "team-c")},
+			expectGranted:   []string{"team-a"},
+			expectDeleted:   []string{"team-c"},
+			expectCondition: metav1.ConditionTrue,
+			expectReason:    "Granted",
+		},
+		{
+			name:           "invalid operator config keeps the current access",
+			share:          sharedSecret("my-share"),
+			operatorConfig: "shareGrants:\n- {sharedSecret: my-share, namespaces: [team-a], serviceAcounts: [builder]}\n",
+			roles:          []*rbacv1.Role{generatedRole("my-share", "team-c")},
+			expectKept:     []string{"team-c"},
+			expectDegraded: "InvalidConfiguration",
+		},
+		{
+			name: "removing the grant revokes the access and the condition",
+			share: sharedSecret("my-share", metav1.Condition{
+				Type:   GrantedConditionType,
+				Status: metav1.ConditionTrue,
+				Reason: "Granted",
+			}),
+			operatorConfig: "shareGrants:\n- {sharedConfigMap: my-share, namespaces: [team-a], serviceAccounts: [builder]}\n",
+			roles:          []*rbacv1.Role{generatedRole("my-share", "team-a")},
+			expectDeleted:  []string{"team-a"},
+		},
+	} {
+		cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		cmIndexer.Add(operatorConfigMap(test.operatorConfig))
+		nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, ns := range []*corev1.Namespace{
+			namespace("team-a", nil),
+			namespace("team-b", map[string]string{"team": "builds"}),
+			namespace("team-c", nil),
+		} {
+			nsIndexer.Add(ns)
+		}
+		roleIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		kubeObjects := []runtime.Object{}
+		for _, role := range test.roles {
+			roleIndexer.Add(role)
+			kubeObjects = append(kubeObjects, role)
+		}
+		shareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		shareIndexer.Add(test.share)
+
+		kubeClient := fake.NewSimpleClientset(kubeObjects...)
+		shareClient := sharefake.NewSimpleClientset(test.share)
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &grantController{
+			kubeClient:            kubeClient,
+			shareClient:           shareClient,
+			operatorClient:        operatorClient,
+			configMapLister:       corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+			namespaceLister:       corev1listers.NewNamespaceLister(nsIndexer),
+			sharedSecretLister:    sharelistersv1alpha1.NewSharedSecretLister(shareIndexer),
+			sharedConfigMapLister: sharelistersv1alpha1.NewSharedConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+			roleLister:            rbacv1listers.NewRoleLister(roleIndexer),
+			roleBindingLister:     rbacv1listers.NewRoleBindingLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		}
+
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, events.NewInMemoryRecorder(controllerName))); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		degraded := v1helpers.FindOperatorCondition(status.Conditions, GrantsDegradedConditionType)
+		switch {
+		case degraded == nil:
+			t.Errorf("testcase %s: expected condition %s, got %v", test.name, GrantsDegradedConditionType, status.Conditions)
+		case len(test.expectDegraded) == 0 && degraded.Status != operatorv1.ConditionFalse:
+			t.Errorf("testcase %s: expected %s to be false, got %v", test.name, GrantsDegradedConditionType, degraded)
+		case len(test.expectDegraded) > 0 && (degraded.Status != operatorv1.ConditionTrue || degraded.Reason != test.expectDegraded):
+			t.Errorf("testcase %s: expected %s with reason %s, got %v", test.name, GrantsDegradedConditionType, test.expectDegraded, degraded)
+		}
+
+		name := grantObjectName(&share{resource: "sharedsecrets", name: test.share.Name})
+		for _, ns := range test.expectGranted {
+			role, err := kubeClient.RbacV1().Roles(ns).Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil {
+				t.Errorf("testcase %s: expected Role in %s, got %v", test.name, ns, err)
+				continue
+			}
+			if role.Rules[0].ResourceNames[0] != test.share.Name || role.Rules[0].Verbs[0] != "use" {
+				t.Errorf("testcase %s: unexpected rules %v", test.name, role.Rules)
+			}
+			binding, err := kubeClient.RbacV1().RoleBindings(ns).Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil {
+				t.Errorf("testcase %s: expected RoleBinding in %s, got %v", test.name, ns, err)
+				continue
+			}
+			if len(binding.Subjects) != 1 || binding.Subjects[0].Name != "builder" || binding.Subjects[0].Namespace != ns {
+				t.Errorf("testcase %s: unexpected subjects %v", test.name, binding.Subjects)
+			}
+		}
+		for _, ns := range test.expectKept {
+			if _, err := kubeClient.RbacV1().Roles(ns).Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+				t.Errorf("testcase %s: expected the Role in %s to be kept, got %v", test.name, ns, err)
+			}
+		}
+		for _, ns := range test.expectDeleted {
+			if _, err := kubeClient.RbacV1().Roles(ns).Get(context.TODO(), name, metav1.GetOptions{}); !kerrors.IsNotFound(err) {
+				t.Errorf("testcase %s: expected the Role in %s to be deleted, got %v", test.name, ns, err)
+			}
+		}
+
+		updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), test.share.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		condition := meta.FindStatusCondition(updated.Status.Conditions, GrantedConditionType)
+		switch {
+		case len(test.expectCondition) == 0 && condition != nil:
+			t.Errorf("testcase %s: expected no %s condition, got %v", test.name, GrantedConditionType, condition)
+		case len(test.expectCondition) > 0 && condition == nil:
+			t.Errorf("testcase %s: expected a %s condition", test.name, GrantedConditionType)
+		case condition != nil && (condition.Status != test.expectCondition || condition.Reason != test.expectReason):
+			t.Errorf("testcase %s: expected condition %s/%s, got %v", test.name, test.expectCondition, test.expectReason, condition)
+		}
+	}
+}
+
+func TestSetConditionKeepsOtherConditions(t *testing.T) {
+	// the lister has not seen the Ready condition another controller has just set
+	cached := sharedSecret("my-share")
+	current := sharedSecret("my-share", metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready"})
+	current.ResourceVersion = "2"
+	shareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	shareIndexer.Add(cached)
+	shareClient := sharefake.NewSimpleClientset(current)
+	c := &grantController{
+		shareClient:           shareClient,
+		sharedSecretLister:    sharelistersv1alpha1.NewSharedSecretLister(shareIndexer),
+		sharedConfigMapLister: sharelistersv1alpha1.NewSharedConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	}
+
+	shares, err := c.listShares()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	granted := &metav1.Condition{Type: GrantedConditionType, Status: metav1.ConditionTrue, Reason: "Granted"}
+	if err := c.setCondition(context.TODO(), shares[0], granted); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), "my-share", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, conditionType := range []string{"Ready", GrantedConditionType} {
+		if meta.FindStatusCondition(updated.Status.Conditions, conditionType) == nil {
+			t.Errorf("expected condition %s, got %v", conditionType, updated.Status.Conditions)
+		}
+	}
+}
+
+func TestEventFilters(t *testing.T) {
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	cmIndexer.Add(operatorConfigMap(`shareGrants:
+- sharedSecret: my-share
+  namespaces: [team-a]
+  namespaceSelector: {matchLabels: {team: builds}}
+  serviceAccounts: [builder]
+`))
+	c := &grantController{
+		configMapLister: corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+	}
+	unlabeledRole := generatedRole("my-share", "team-a")
+	unlabeledRole.Labels = nil
+
+	for _, test := range []struct {
+		name   string
+		filter factory.EventFilterFunc
+		obj    interface{}
+		expect bool
+	}{
+		{name: "named namespace", filter: c.isGrantNamespace, obj: namespace("team-a", nil), expect: true},
+		{name: "selected namespace", filter: c.isGrantNamespace, obj: namespace("team-b", map[string]string{"team": "builds"}), expect: true},
+		{name: "other namespace", filter: c.isGrantNamespace, obj: namespace("team-c", nil), expect: false},
+		{name: "namespace tombstone", filter: c.isGrantNamespace, obj: cache.DeletedFinalStateUnknown{Key: "team-c"}, expect: true},
+		{name: "generated role", filter: isGenerated, obj: generatedRole("my-share", "team-a"), expect: true},
+		{name: "other role", filter: isGenerated, obj: unlabeledRole, expect: false},
+		{name: "role tombstone", filter: isGenerated, obj: cache.DeletedFinalStateUnknown{Key: "team-a/role"}, expect: true},
+	} {
+		if got := test.filter(test.obj); got != test.expect {
+			t.Errorf("testcase %s: expected %v, got %v", test.name, test.expect, got)
+		}
+	}
+
+	cmIndexer.Update(operatorConfigMap("shareGrants: [{sharedSecret: my-share, namespaces: [team-a], serviceAcounts: [builder]}]\n"))
+	if c.isGrantNamespace(namespace("team-a", nil)) {
+		t.Errorf("expected namespace events to be ignored while the operator configuration is invalid")
+	}
+}
@@ -0,0 +1,37 @@
+package hooks
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	dc "github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+)
+
+const (
+	// hostedControlPlaneLabel marks the pods of a hosted control plane with the namespace of that control plane
+	hostedControlPlaneLabel = "hypershift.openshift.io/hosted-control-plane"
+	masterNodeRole          = "node-role.kubernetes.io/master"
+)
+
+// WithHostedControlPlaneDeploymentHook renders the webhook Deployment for the management cluster of a hosted
+// control plane: its pods are labelled as part of the control plane in namespace and are no longer pinned to
+// master nodes, which management clusters do not run workloads on.
+func WithHostedControlPlaneDeploymentHook(namespace string) dc.DeploymentHookFunc {
+	return func(_ *opv1.OperatorSpec, deployment *appsv1.Deployment) error {
+		if deployment.Spec.Template.Labels == nil {
+			deployment.Spec.Template.Labels = map[string]string{}
+		}
+		deployment.Spec.Template.Labels[hostedControlPlaneLabel] = namespace
+		delete(deployment.Spec.Template.Spec.NodeSelector, masterNodeRole)
+
+		tolerations := []corev1.Toleration{}
+		for _, toleration := range deployment.Spec.Template.Spec.Tolerations {
+			if toleration.Key != masterNodeRole {
+				tolerations = append(tolerations, toleration)
+			}
+		}
+		deployment.Spec.Template.Spec.Tolerations = tolerations
+		return nil
+	}
+}
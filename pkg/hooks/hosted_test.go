@@ -0,0 +1,35 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+)
+
+func TestHostedControlPlaneDeploymentHook(t *testing.T) {
+	deployment := resourceread.ReadDeploymentV1OrDie(assets.MustAsset("webhook/deployment.yaml"))
+	if err := WithHostedControlPlaneDeploymentHook("clusters-guest")(nil, deployment); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	podSpec := deployment.Spec.Template.Spec
+	if _, ok := podSpec.NodeSelector[masterNodeRole]; ok {
+		t.Errorf("expected no %s node selector, got %v", masterNodeRole, podSpec.NodeSelector)
+	}
+	for _, toleration := range podSpec.Tolerations {
+		if toleration.Key == masterNodeRole {
+			t.Errorf("expected no %s toleration, got %v", masterNodeRole, podSpec.Tolerations)
+		}
+	}
+	if len(podSpec.Tolerations) != 1 {
+		t.Errorf("expected the other tolerations to be kept, got %v", podSpec.Tolerations)
+	}
+	if value := deployment.Spec.Template.Labels[hostedControlPlaneLabel]; value != "clusters-guest" {
+		t.Errorf("expected label %s=clusters-guest, got %v", hostedControlPlaneLabel, deployment.Spec.Template.Labels)
+	}
+	if deployment.Spec.Template.Labels["name"] != "shared-resource-csi-driver-webhook" {
+		t.Errorf("expected the selector label to be kept, got %v", deployment.Spec.Template.Labels)
+	}
+}
@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/csi/csidrivernodeservicecontroller"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+// WithLivenessProbeDaemonSetHook sets the liveness probe thresholds of the operator configuration on the driver
+// container. The configured timeout is also passed to the csi-liveness-probe sidecar, so that it gives up on the
+// driver before the kubelet gives up on it.
+func WithLivenessProbeDaemonSetHook(configMapLister corev1listers.ConfigMapNamespaceLister) csidrivernodeservicecontroller.DaemonSetHookFunc {
+	return func(_ *opv1.OperatorSpec, ds *appsv1.DaemonSet) error {
+		opConfig, err := config.GetOperatorConfig(configMapLister)
+		if err != nil {
+			return err
+		}
+		thresholds := opConfig.LivenessProbe
+		if thresholds == nil {
+			return nil
+		}
+		containers := ds.Spec.Template.Spec.Containers
+		for i := range containers {
+			switch containers[i].Name {
+			case hostpathContainerName:
+				probe := containers[i].LivenessProbe
+				if probe == nil {
+					return fmt.Errorf("container %s has no liveness probe", hostpathContainerName)
+				}
+				if thresholds.InitialDelaySeconds != nil {
+					probe.InitialDelaySeconds = *thresholds.InitialDelaySeconds
+				}
+				if thresholds.PeriodSeconds != nil {
+					probe.PeriodSeconds = *thresholds.PeriodSeconds
+				}
+				if thresholds.TimeoutSeconds != nil {
+					probe.TimeoutSeconds = *thresholds.TimeoutSeconds
+				}
+				if thresholds.FailureThreshold != nil {
+					probe.FailureThreshold = *thresholds.FailureThreshold
+				}
+			case livenessProbeContainerName:
+				if thresholds.TimeoutSeconds == nil {
+					continue
+				}
+				for j, arg := range containers[i].Args {
+					if strings.HasPrefix(arg, "--probe-timeout=") {
+						containers[i].Args[j] = fmt.Sprintf("--probe-timeout=%ds", *thresholds.TimeoutSeconds)
+					}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// WithLivenessProbeSidecarDaemonSetHook removes the csi-liveness-probe sidecar, and the liveness probe of the driver
+// container it answers, when image is empty. library-go only replaces ${LIVENESS_PROBE_IMAGE} when the
+// LIVENESS_PROBE_IMAGE environment variable of the operator is set, and the driver pods cannot pull the placeholder.
+func WithLivenessProbeSidecarDaemonSetHook(image string) csidrivernodeservicecontroller.DaemonSetHookFunc {
+	return func(_ *opv1.OperatorSpec, ds *appsv1.DaemonSet) error {
+		if len(image) > 0 {
+			return nil
+		}
+		containers := []corev1.Container{}
+		for _, container := range ds.Spec.Template.Spec.Containers {
+			switch container.Name {
+			case livenessProbeContainerName:
+				continue
+			case hostpathContainerName:
+				container.LivenessProbe = nil
+			}
+			containers = append(containers, container)
+		}
+		ds.Spec.Template.Spec.Containers = containers
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package hooks
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+)
+
+func TestLivenessProbeDaemonSetHook(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		operatorConfig    string
+		expectErr         bool
+		expectProbe       corev1.Probe
+		expectTimeoutFlag string
+	}{
+		{
+			name:              "manifest thresholds",
+			expectProbe:       corev1.Probe{InitialDelaySeconds: 10, PeriodSeconds: 10, TimeoutSeconds: 3, FailureThreshold: 5},
+			expectTimeoutFlag: "--probe-timeout=3s",
+		},
+		{
+			name: "configured thresholds",
+			operatorConfig: `livenessProbe:
+  periodSeconds: 30
+  timeoutSeconds: 10
+  failureThreshold: 3
+`,
+			expectProbe:       corev1.Probe{InitialDelaySeconds: 10, PeriodSeconds: 30, TimeoutSeconds: 10, FailureThreshold: 3},
+			expectTimeoutFlag: "--probe-timeout=10s",
+		},
+		{
+			name: "invalid thresholds",
+			operatorConfig: `livenessProbe:
+  failureThreshold: 0
+`,
+			expectErr: true,
+		},
+	} {
+		ds := resourceread.ReadDaemonSetV1OrDie(assets.MustAsset("node.yaml"))
+		err := WithLivenessProbeDaemonSetHook(operatorConfigLister(test.operatorConfig))(nil, ds)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("testcase %s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		for _, container := range ds.Spec.Template.Spec.Containers {
+			switch container.Name {
+			case hostpathContainerName:
+				probe := container.LivenessProbe
+				if probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Port.String() != "healthz" {
+					t.Fatalf("testcase %s: expected an HTTP liveness probe on port healthz, got %v", test.name, probe)
+				}
+				if probe.InitialDelaySeconds != test.expectProbe.InitialDelaySeconds || probe.PeriodSeconds != test.expectProbe.PeriodSeconds ||
+					probe.TimeoutSeconds != test.expectProbe.TimeoutSeconds || probe.FailureThreshold != test.expectProbe.FailureThreshold {
+					t.Errorf("testcase %s: expected thresholds %+v, got %+v", test.name, test.expectProbe, probe)
+				}
+			case livenessProbeContainerName:
+				found := false
+				for _, arg := range container.Args {
+					found = found || arg == test.expectTimeoutFlag
+				}
+				if !found {
+					t.Errorf("testcase %s: expected argument %s, got %v", test.name, test.expectTimeoutFlag, container.Args)
+				}
+			}
+		}
+	}
+}
+
+func TestLivenessProbeSidecarDaemonSetHook(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		image        string
+		expectProbes bool
+	}{
+		{
+			name:         "image set",
+			image:        "quay.io/openshift/origin-csi-livenessprobe:latest",
+			expectProbes: true,
+		},
+		{
+			name: "image not set",
+		},
+	} {
+		ds := resourceread.ReadDaemonSetV1OrDie(assets.MustAsset("node.yaml"))
+		if err := WithLivenessProbeSidecarDaemonSetHook(test.image)(nil, ds); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		sidecar, probe, names := false, false, []string{}
+		for _, container := range ds.Spec.Template.Spec.Containers {
+			names = append(names, container.Name)
+			sidecar = sidecar || container.Name == livenessProbeContainerName
+			if container.Name == hostpathContainerName {
+				probe = container.LivenessProbe != nil
+			}
+		}
+		if sidecar != test.expectProbes || probe != test.expectProbes {
+			t.Errorf("testcase %s: expected the sidecar and the driver liveness probe to be kept %v, got sidecar %v and probe %v", test.name, test.expectProbes, sidecar, probe)
+		}
+		if len(names) < 2 || names[0] != nodeDriverRegistrarContainerName || names[1] != hostpathContainerName {
+			t.Errorf("testcase %s: expected the other containers to be kept, got %v", test.name, names)
+		}
+	}
+}
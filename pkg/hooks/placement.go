@@ -0,0 +1,31 @@
+package hooks
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/csi/csidrivernodeservicecontroller"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+// WithNodePlacementDaemonSetHook sets the node placement of the operator configuration on the driver DaemonSet.
+// The node selector, tolerations and affinity of node.yaml are kept when no placement is configured.
+func WithNodePlacementDaemonSetHook(configMapLister corev1listers.ConfigMapNamespaceLister) csidrivernodeservicecontroller.DaemonSetHookFunc {
+	return func(_ *opv1.OperatorSpec, ds *appsv1.DaemonSet) error {
+		opConfig, err := config.GetOperatorConfig(configMapLister)
+		if err != nil {
+			return err
+		}
+		placement := opConfig.NodePlacement
+		if placement == nil {
+			return nil
+		}
+		podSpec := &ds.Spec.Template.Spec
+		podSpec.NodeSelector = placement.NodeSelector
+		podSpec.Tolerations = placement.Tolerations
+		podSpec.Affinity = placement.Affinity
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package hooks
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+)
+
+func TestNodePlacementDaemonSetHook(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		operatorConfig    string
+		expectErr         bool
+		expectSelector    map[string]string
+		expectTolerations []corev1.Toleration
+		expectAffinity    bool
+	}{
+		{
+			name: "no placement",
+		},
+		{
+			name: "configured placement",
+			operatorConfig: `nodePlacement:
+  nodeSelector:
+    node-role.kubernetes.io/worker: ""
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+  affinity:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+        - matchExpressions:
+          - key: node-role.kubernetes.io/edge
+            operator: DoesNotExist
+`,
+			expectSelector:    map[string]string{"node-role.kubernetes.io/worker": ""},
+			expectTolerations: []corev1.Toleration{{Key: "node-role.kubernetes.io/infra", Operator: corev1.TolerationOpExists}},
+			expectAffinity:    true,
+		},
+		{
+			name: "invalid placement",
+			operatorConfig: `nodePlacement:
+  tolerations:
+  - operator: Sometimes
+`,
+			expectErr: true,
+		},
+	} {
+		ds := resourceread.ReadDaemonSetV1OrDie(assets.MustAsset("node.yaml"))
+		err := WithNodePlacementDaemonSetHook(operatorConfigLister(test.operatorConfig))(nil, ds)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("testcase %s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		podSpec := ds.Spec.Template.Spec
+		if !reflect.DeepEqual(podSpec.NodeSelector, test.expectSelector) {
+			t.Errorf("testcase %s: expected node selector %v, got %v", test.name, test.expectSelector, podSpec.NodeSelector)
+		}
+		if !reflect.DeepEqual(podSpec.Tolerations, test.expectTolerations) {
+			t.Errorf("testcase %s: expected tolerations %v, got %v", test.name, test.expectTolerations, podSpec.Tolerations)
+		}
+		if (podSpec.Affinity != nil) != test.expectAffinity {
+			t.Errorf("testcase %s: expected affinity %v, got %v", test.name, test.expectAffinity, podSpec.Affinity)
+		}
+	}
+}
+
+// TestDriverUnregistersOnTermination checks the parts of the DaemonSet that the node-driver-registrar relies on to
+// unregister the driver when its pod is deleted, e.g. because the node no longer matches the node placement: the
+// registrar is sent SIGTERM, with no preStop hook racing it, and removes its registration socket from the
+// plugins_registry directory of the kubelet, which then drops the driver from the CSINode of the node.
+func TestDriverUnregistersOnTermination(t *testing.T) {
+	ds := resourceread.ReadDaemonSetV1OrDie(assets.MustAsset("node.yaml"))
+	podSpec := ds.Spec.Template.Spec
+	if podSpec.TerminationGracePeriodSeconds != nil && *podSpec.TerminationGracePeriodSeconds == 0 {
+		t.Errorf("expected the registrar to be given time to clean up, got a termination grace period of 0")
+	}
+
+	registryVolume := ""
+	for _, volume := range podSpec.Volumes {
+		if volume.HostPath != nil && volume.HostPath.Path == "/var/lib/kubelet/plugins_registry" {
+			registryVolume = volume.Name
+		}
+	}
+	if len(registryVolume) == 0 {
+		t.Fatalf("expected the kubelet plugins_registry directory to be mounted, got volumes %v", podSpec.Volumes)
+	}
+
+	found := false
+	for _, container := range podSpec.Containers {
+		if container.Name != nodeDriverRegistrarContainerName {
+			continue
+		}
+		found = true
+		if container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+			t.Errorf("expected no preStop hook on %s, got %v", container.Name, container.Lifecycle.PreStop)
+		}
+		mounted := false
+		for _, mount := range container.VolumeMounts {
+			mounted = mounted || (mount.Name == registryVolume && mount.MountPath == "/registration")
+		}
+		if !mounted {
+			t.Errorf("expected %s to mount the plugins_registry directory at /registration, got %v", container.Name, container.VolumeMounts)
+		}
+	}
+	if !found {
+		t.Errorf("expected a %s container", nodeDriverRegistrarContainerName)
+	}
+}
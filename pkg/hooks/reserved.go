@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/csi/csidrivernodeservicecontroller"
+	dc "github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+// WithReservedNamesDaemonSetHook sets the reserved share names of the operator configuration on the driver
+// DaemonSet containers that declare the reserved names environment variables.
+func WithReservedNamesDaemonSetHook(configMapLister corev1listers.ConfigMapNamespaceLister) csidrivernodeservicecontroller.DaemonSetHookFunc {
+	return func(_ *opv1.OperatorSpec, ds *appsv1.DaemonSet) error {
+		env, err := reservedNamesEnv(configMapLister)
+		if err != nil {
+			return err
+		}
+		setEnv(ds.Spec.Template.Spec.Containers, env)
+		return nil
+	}
+}
+
+// WithReservedNamesDeploymentHook sets the reserved share names of the operator configuration on the webhook
+// Deployment containers that declare the reserved names environment variables.
+func WithReservedNamesDeploymentHook(configMapLister corev1listers.ConfigMapNamespaceLister) dc.DeploymentHookFunc {
+	return func(_ *opv1.OperatorSpec, deployment *appsv1.Deployment) error {
+		env, err := reservedNamesEnv(configMapLister)
+		if err != nil {
+			return err
+		}
+		setEnv(deployment.Spec.Template.Spec.Containers, env)
+		return nil
+	}
+}
+
+func reservedNamesEnv(configMapLister corev1listers.ConfigMapNamespaceLister) (map[string]string, error) {
+	opConfig, err := config.GetOperatorConfig(configMapLister)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		config.ReservedSharedSecretNamesEnv:    config.FormatReservedNames(opConfig.GetReservedSharedSecretNames()),
+		config.ReservedSharedConfigMapNamesEnv: config.FormatReservedNames(opConfig.GetReservedSharedConfigMapNames()),
+	}, nil
+}
+
+// setEnv sets the value of the variables in env that containers already declare. Variables are not added to
+// containers that do not declare them.
+func setEnv(containers []corev1.Container, env map[string]string) {
+	for i := range containers {
+		for j := range containers[i].Env {
+			if value, ok := env[containers[i].Env[j].Name]; ok {
+				containers[i].Env[j].Value = value
+				containers[i].Env[j].ValueFrom = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package hooks
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func operatorConfigLister(operatorConfig string) corev1listers.ConfigMapNamespaceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if len(operatorConfig) > 0 {
+		indexer.Add(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: config.OperatorConfigMapName, Namespace: config.DefaultNamespace},
+			Data:       map[string]string{config.ConfigKey: operatorConfig},
+		})
+	}
+	return corev1listers.NewConfigMapLister(indexer).ConfigMaps(config.DefaultNamespace)
+}
+
+func envValue(containers []corev1.Container, container, name string) (string, bool) {
+	for _, c := range containers {
+		if c.Name != container {
+			continue
+		}
+		for _, env := range c.Env {
+			if env.Name == name {
+				return env.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func TestReservedNamesHooks(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		operatorConfig string
+		expectErr      bool
+		expectSecrets  string
+		expectCMs      string
+	}{
+		{
+			name:          "no operator configuration",
+			expectSecrets: "openshift-etc-pki-entitlement: openshift-config-managed:etc-pki-entitlement",
+		},
+		{
+			name: "configured reservations",
+			operatorConfig: `reservedSharedConfigMapNames:
+  trusted-ca: openshift-config-managed:trusted-ca-bundle
+`,
+			expectSecrets: "openshift-etc-pki-entitlement: openshift-config-managed:etc-pki-entitlement",
+			expectCMs:     "trusted-ca: openshift-config-managed:trusted-ca-bundle",
+		},
+		{
+			name: "invalid reservation",
+			operatorConfig: `reservedSharedConfigMapNames:
+  trusted-ca: trusted-ca-bundle
+`,
+			expectErr: true,
+		},
+	} {
+		lister := operatorConfigLister(test.operatorConfig)
+
+		ds := resourceread.ReadDaemonSetV1OrDie(assets.MustAsset("node.yaml"))
+		dsErr := WithReservedNamesDaemonSetHook(lister)(nil, ds)
+		deployment := resourceread.ReadDeploymentV1OrDie(assets.MustAsset("webhook/deployment.yaml"))
+		deploymentErr := WithReservedNamesDeploymentHook(lister)(nil, deployment)
+
+		if test.expectErr {
+			if dsErr == nil || deploymentErr == nil {
+				t.Errorf("testcase %s: expected errors, got %v and %v", test.name, dsErr, deploymentErr)
+			}
+			continue
+		}
+		if dsErr != nil || deploymentErr != nil {
+			t.Fatalf("testcase %s: unexpected errors %v and %v", test.name, dsErr, deploymentErr)
+		}
+
+		for _, target := range []struct {
+			containers []corev1.Container
+			container  string
+		}{
+			{ds.Spec.Template.Spec.Containers, "hostpath"},
+			{deployment.Spec.Template.Spec.Containers, "shared-resource-csi-driver-webhook"},
+		} {
+			for env, expected := range map[string]string{
+				config.ReservedSharedSecretNamesEnv:    test.expectSecrets,
+				config.ReservedSharedConfigMapNamesEnv: test.expectCMs,
+			} {
+				value, ok := envValue(target.containers, target.container, env)
+				if !ok {
+					t.Errorf("testcase %s: container %s does not declare %s", test.name, target.container, env)
+				}
+				if value != expected {
+					t.Errorf("testcase %s: expected %s=%q on container %s, got %q", test.name, env, expected, target.container, value)
+				}
+			}
+		}
+		if _, ok := envValue(ds.Spec.Template.Spec.Containers, "node-driver-registrar", config.ReservedSharedSecretNamesEnv); ok {
+			t.Errorf("testcase %s: reserved names were added to node-driver-registrar", test.name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package hooks
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/csi/csidrivernodeservicecontroller"
+	dc "github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+const (
+	hostpathContainerName            = "hostpath"
+	nodeDriverRegistrarContainerName = "node-driver-registrar"
+	livenessProbeContainerName       = "csi-liveness-probe"
+	webhookContainerName             = "shared-resource-csi-driver-webhook"
+)
+
+// WithResourcesDaemonSetHook overrides the resources of the driver DaemonSet containers with those of the
+// operator configuration.
+func WithResourcesDaemonSetHook(configMapLister corev1listers.ConfigMapNamespaceLister) csidrivernodeservicecontroller.DaemonSetHookFunc {
+	return func(_ *opv1.OperatorSpec, ds *appsv1.DaemonSet) error {
+		opConfig, err := config.GetOperatorConfig(configMapLister)
+		if err != nil {
+			return err
+		}
+		if opConfig.Resources == nil {
+			return nil
+		}
+		return overrideResources(ds.Spec.Template.Spec.Containers, map[string]*corev1.ResourceRequirements{
+			hostpathContainerName:            opConfig.Resources.Hostpath,
+			nodeDriverRegistrarContainerName: opConfig.Resources.NodeDriverRegistrar,
+			livenessProbeContainerName:       opConfig.Resources.LivenessProbe,
+		})
+	}
+}
+
+// WithResourcesDeploymentHook overrides the resources of the webhook Deployment container with those of the
+// operator configuration.
+func WithResourcesDeploymentHook(configMapLister corev1listers.ConfigMapNamespaceLister) dc.DeploymentHookFunc {
+	return func(_ *opv1.OperatorSpec, deployment *appsv1.Deployment) error {
+		opConfig, err := config.GetOperatorConfig(configMapLister)
+		if err != nil {
+			return err
+		}
+		if opConfig.Resources == nil {
+			return nil
+		}
+		return overrideResources(deployment.Spec.Template.Spec.Containers, map[string]*corev1.ResourceRequirements{
+			webhookContainerName: opConfig.Resources.Webhook,
+		})
+	}
+}
+
+// overrideResources sets, on the containers named in overrides, each request and limit the override names.
+// The result is validated, as an override can put a limit below the request of the manifest.
+func overrideResources(containers []corev1.Container, overrides map[string]*corev1.ResourceRequirements) error {
+	errs := field.ErrorList{}
+	for i := range containers {
+		override := overrides[containers[i].Name]
+		if override == nil {
+			continue
+		}
+		resources := &containers[i].Resources
+		for name, quantity := range override.Requests {
+			if resources.Requests == nil {
+				resources.Requests = corev1.ResourceList{}
+			}
+			resources.Requests[name] = quantity
+		}
+		for name, quantity := range override.Limits {
+			if resources.Limits == nil {
+				resources.Limits = corev1.ResourceList{}
+			}
+			resources.Limits[name] = quantity
+		}
+		errs = append(errs, config.ValidateResourceRequirements(field.NewPath("containers").Key(containers[i].Name).Child("resources"), resources)...)
+	}
+	if err := errs.ToAggregate(); err != nil {
+		return fmt.Errorf("invalid resources: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+)
+
+func containerResources(containers []corev1.Container, name string) corev1.ResourceRequirements {
+	for _, c := range containers {
+		if c.Name == name {
+			return c.Resources
+		}
+	}
+	return corev1.ResourceRequirements{}
+}
+
+func TestResourcesHooks(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		operatorConfig string
+		expectErr      string
+		// expected requests and limits, as cpu/memory, per container
+		expectRequests map[string]string
+		expectLimits   map[string]string
+	}{
+		{
+			name: "manifest resources",
+			expectRequests: map[string]string{
+				hostpathContainerName:            "10m/20Mi",
+				nodeDriverRegistrarContainerName: "10m/20Mi",
+				webhookContainerName:             "10m/20Mi",
+			},
+			expectLimits: map[string]string{},
+		},
+		{
+			name: "overrides",
+			operatorConfig: `resources:
+  hostpath:
+    requests:
+      cpu: 100m
+      memory: 256Mi
+    limits:
+      memory: 1Gi
+  webhook:
+    limits:
+      memory: 100Mi
+`,
+			expectRequests: map[string]string{
+				hostpathContainerName:            "100m/256Mi",
+				nodeDriverRegistrarContainerName: "10m/20Mi",
+				webhookContainerName:             "10m/20Mi",
+			},
+			expectLimits: map[string]string{
+				hostpathContainerName: "0/1Gi",
+				webhookContainerName:  "0/100Mi",
+			},
+		},
+		{
+			name: "invalid quantity",
+			operatorConfig: `resources:
+  hostpath:
+    requests:
+      memory: 1 gigabyte
+`,
+			expectErr: "quantities must match the regular expression",
+		},
+		{
+			name: "limit below the manifest request",
+			operatorConfig: `resources:
+  nodeDriverRegistrar:
+    limits:
+      memory: 10Mi
+  webhook:
+    limits:
+      cpu: 5m
+`,
+			expectErr: "must not be greater than the limit of",
+		},
+	} {
+		lister := operatorConfigLister(test.operatorConfig)
+
+		ds := resourceread.ReadDaemonSetV1OrDie(assets.MustAsset("node.yaml"))
+		dsErr := WithResourcesDaemonSetHook(lister)(nil, ds)
+		deployment := resourceread.ReadDeploymentV1OrDie(assets.MustAsset("webhook/deployment.yaml"))
+		deploymentErr := WithResourcesDeploymentHook(lister)(nil, deployment)
+
+		if len(test.expectErr) > 0 {
+			if dsErr == nil || !strings.Contains(dsErr.Error(), test.expectErr) {
+				t.Errorf("testcase %s: expected DaemonSet error containing %q, got %v", test.name, test.expectErr, dsErr)
+			}
+			if deploymentErr == nil || !strings.Contains(deploymentErr.Error(), test.expectErr) {
+				t.Errorf("testcase %s: expected Deployment error containing %q, got %v", test.name, test.expectErr, deploymentErr)
+			}
+			continue
+		}
+		if dsErr != nil || deploymentErr != nil {
+			t.Fatalf("testcase %s: unexpected errors %v, %v", test.name, dsErr, deploymentErr)
+		}
+
+		containers := append(ds.Spec.Template.Spec.Containers, deployment.Spec.Template.Spec.Containers...)
+		for _, name := range []string{hostpathContainerName, nodeDriverRegistrarContainerName, webhookContainerName} {
+			resources := containerResources(containers, name)
+			requests := resources.Requests.Cpu().String() + "/" + resources.Requests.Memory().String()
+			if requests != test.expectRequests[name] {
+				t.Errorf("testcase %s: expected requests %s on container %s, got %s", test.name, test.expectRequests[name], name, requests)
+			}
+			limits := ""
+			if len(resources.Limits) > 0 {
+				limits = resources.Limits.Cpu().String() + "/" + resources.Limits.Memory().String()
+			}
+			if limits != test.expectLimits[name] {
+				t.Errorf("testcase %s: expected limits %q on container %s, got %q", test.name, test.expectLimits[name], name, limits)
+			}
+		}
+	}
+}
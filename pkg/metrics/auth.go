@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// authCacheTTL is how long the answer of a token review or an access review is reused; it is kept short so
+	// that revoking the access of a scraper takes effect quickly
+	authCacheTTL = 10 * time.Second
+	// authReviewTimeout bounds a token review or an access review
+	authReviewTimeout = 10 * time.Second
+	// authVerb is the verb a user needs on the non-resource URL of the request
+	authVerb = "get"
+)
+
+var now = time.Now
+
+// AuthFilter restricts a handler to the requests whose bearer token is accepted by a TokenReview, and whose user a
+// SubjectAccessReview allows to get the non-resource URL of the request, for example /metrics.
+type AuthFilter struct {
+	tokenReviews  authenticationv1client.TokenReviewInterface
+	accessReviews authorizationv1client.SubjectAccessReviewInterface
+
+	// users caches the token reviews by token hash, decisions the access reviews by user and path
+	users     *reviewCache[authenticationv1.TokenReviewStatus]
+	decisions *reviewCache[authorizationv1.SubjectAccessReviewStatus]
+}
+
+// NewAuthFilter creates an AuthFilter sending its reviews through authenticationClient and authorizationClient.
+func NewAuthFilter(authenticationClient authenticationv1client.AuthenticationV1Interface, authorizationClient authorizationv1client.AuthorizationV1Interface) *AuthFilter {
+	return &AuthFilter{
+		tokenReviews:  authenticationClient.TokenReviews(),
+		accessReviews: authorizationClient.SubjectAccessReviews(),
+		users:         newReviewCache[authenticationv1.TokenReviewStatus](authCacheTTL),
+		decisions:     newReviewCache[authorizationv1.SubjectAccessReviewStatus](authCacheTTL),
+	}
+}
+
+// WithAuth wraps handler so that it only serves authenticated and authorized requests.
+func (f *AuthFilter) WithAuth(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), authReviewTimeout)
+		defer cancel()
+
+		status, err := f.authenticate(ctx, token)
+		if err != nil {
+			klog.Errorf("Unable to authenticate metrics request from %s: %v", r.RemoteAddr, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !status.Authenticated {
+			klog.V(4).Infof("Metrics request from %s not authenticated: %s", r.RemoteAddr, status.Error)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		decision, err := f.authorize(ctx, status.User, r.URL.Path)
+		if err != nil {
+			klog.Errorf("Unable to authorize metrics request of %q: %v", status.User.Username, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !decision.Allowed || decision.Denied {
+			klog.V(4).Infof("Metrics request of %q forbidden: %s", status.User.Username, decision.Reason)
+			http.Error(w, fmt.Sprintf("Forbidden (user=%s, verb=%s, nonResourceURL=%s)", status.User.Username, authVerb, r.URL.Path), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, len(token) > 0
+}
+
+// authenticate reviews token, caching the answer under its hash so that the tokens themselves are not kept.
+func (f *AuthFilter) authenticate(ctx context.Context, token string) (authenticationv1.TokenReviewStatus, error) {
+	hash := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(hash[:])
+	if status, ok := f.users.get(key); ok {
+		return status, nil
+	}
+	review, err := f.tokenReviews.Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.TokenReviewStatus{}, err
+	}
+	f.users.set(key, review.Status)
+	return review.Status, nil
+}
+
+// authorize reviews whether user can get path, caching the answer under the user and path.
+func (f *AuthFilter) authorize(ctx context.Context, user authenticationv1.UserInfo, path string) (authorizationv1.SubjectAccessReviewStatus, error) {
+	groups := append([]string{}, user.Groups...)
+	sort.Strings(groups)
+	key := strings.Join([]string{user.Username, user.UID, strings.Join(groups, ","), path}, "\x00")
+	if status, ok := f.decisions.get(key); ok {
+		return status, nil
+	}
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := f.accessReviews.Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: path,
+				Verb: authVerb,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, err
+	}
+	f.decisions.set(key, review.Status)
+	return review.Status, nil
+}
+
+// reviewCache keeps review answers for ttl.
+type reviewCache[T any] struct {
+	ttl time.Duration
+
+	lock    sync.Mutex
+	entries map[string]reviewCacheEntry[T]
+}
+
+type reviewCacheEntry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+func newReviewCache[T any](ttl time.Duration) *reviewCache[T] {
+	return &reviewCache[T]{ttl: ttl, entries: map[string]reviewCacheEntry[T]{}}
+}
+
+func (c *reviewCache[T]) get(key string) (T, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now().Before(entry.expires) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// set caches value under key, dropping the expired entries so that the cache does not grow with the callers.
+func (c *reviewCache[T]) set(key string, value T) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := now()
+	for k, entry := range c.entries {
+		if !t.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = reviewCacheEntry[T]{value: value, expires: t.Add(c.ttl)}
+}
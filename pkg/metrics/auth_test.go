@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const (
+	scraperToken = "scraper-token"
+	scraperUser  = "system:serviceaccount:openshift-monitoring:prometheus-k8s"
+	otherToken   = "other-token"
+	otherUser    = "system:serviceaccount:default:other"
+)
+
+// fakeAuthClient accepts scraperToken and otherToken, and allows scraperUser to get /metrics. It counts the reviews
+// it answers.
+func fakeAuthClient(authzErr error) (*fake.Clientset, *int, *int) {
+	tokenReviews, accessReviews := 0, 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		switch review.Spec.Token {
+		case scraperToken:
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: scraperUser}}
+		case otherToken:
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: otherUser}}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		accessReviews++
+		if authzErr != nil {
+			return true, nil, authzErr
+		}
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		attributes := review.Spec.NonResourceAttributes
+		review.Status.Allowed = review.Spec.User == scraperUser && attributes != nil && attributes.Verb == "get" && attributes.Path == "/metrics"
+		return true, review, nil
+	})
+	return client, &tokenReviews, &accessReviews
+}
+
+func TestAuthFilter(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		token         string
+		authzErr      error
+		requests      int
+		expectStatus  int
+		expectReviews int
+	}{
+		{
+			name:         "no token",
+			requests:     1,
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "invalid token",
+			token:        "invalid",
+			requests:     1,
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "forbidden user",
+			token:         otherToken,
+			requests:      1,
+			expectStatus:  http.StatusForbidden,
+			expectReviews: 1,
+		},
+		{
+			name:          "allowed user is cached",
+			token:         scraperToken,
+			requests:      3,
+			expectStatus:  http.StatusOK,
+			expectReviews: 1,
+		},
+		{
+			name:          "failed access review",
+			token:         scraperToken,
+			authzErr:      fmt.Errorf("apiserver unavailable"),
+			requests:      1,
+			expectStatus:  http.StatusInternalServerError,
+			expectReviews: 1,
+		},
+	} {
+		client, tokenReviews, accessReviews := fakeAuthClient(test.authzErr)
+		authFilter := NewAuthFilter(client.AuthenticationV1(), client.AuthorizationV1())
+		handler := authFilter.WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		for i := 0; i < test.requests; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if len(test.token) > 0 {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != test.expectStatus {
+				t.Errorf("testcase %s: request %d: expected status %d, got %d", test.name, i, test.expectStatus, w.Code)
+			}
+		}
+		if len(test.token) > 0 && *tokenReviews != 1 {
+			t.Errorf("testcase %s: expected the token review to be cached, got %d reviews", test.name, *tokenReviews)
+		}
+		if *accessReviews != test.expectReviews {
+			t.Errorf("testcase %s: expected %d access reviews, got %d", test.name, test.expectReviews, *accessReviews)
+		}
+	}
+}
+
+func TestAuthCacheExpiry(t *testing.T) {
+	start := time.Now()
+	defer func() { now = time.Now }()
+	now = func() time.Time { return start }
+
+	client, tokenReviews, accessReviews := fakeAuthClient(nil)
+	handler := NewAuthFilter(client.AuthenticationV1(), client.AuthorizationV1()).WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, elapsed := range []time.Duration{0, authCacheTTL / 2, authCacheTTL} {
+		now = func() time.Time { return start.Add(elapsed) }
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set("Authorization", "Bearer "+scraperToken)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("after %v: expected status %d, got %d", elapsed, http.StatusOK, w.Code)
+		}
+	}
+	if *tokenReviews != 2 || *accessReviews != 2 {
+		t.Errorf("expected the reviews to be made again once cached for %v, got %d token and %d access reviews", authCacheTTL, *tokenReviews, *accessReviews)
+	}
+}
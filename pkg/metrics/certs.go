@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+const certExpiryName = sharesSubsystem + separator + "metrics_cert_expiry_timestamp_seconds"
+
+var certExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: certExpiryName,
+	Help: "Expiry of the certificate served by the metrics server, in seconds since the epoch",
+})
+
+func init() {
+	prometheus.MustRegister(certExpiry)
+}
+
+// certReloader serves the key pair in certFile and keyFile, reloading it when the files change so that a rotated
+// serving certificate is picked up without restarting the server.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	cert atomic.Pointer[tls.Certificate]
+}
+
+// newCertReloader loads the key pair in certFile and keyFile, failing when it cannot be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload replaces the served key pair with the one in the files, keeping the current one when they cannot be loaded.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load key pair %s, %s: %v", r.certFile, r.keyFile, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("unable to parse certificate %s: %v", r.certFile, err)
+	}
+	cert.Leaf = leaf
+	r.cert.Store(&cert)
+	certExpiry.Set(float64(leaf.NotAfter.Unix()))
+	return nil
+}
+
+// GetCertificate is the tls.Config callback returning the last key pair loaded.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// watch reloads the key pair on changes to the directories of the files until stopCh is closed. The directories
+// are watched rather than the files, as secret volumes are updated by swapping a symlink.
+func (r *certReloader) watch(stopCh <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if err := r.reload(); err != nil {
+					klog.Errorf("error reloading metrics serving certificate after %s: %v", event, err)
+					continue
+				}
+				klog.V(2).Infof("Reloaded metrics serving certificate %s", r.certFile)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.Errorf("error watching metrics serving certificate: %v", err)
+			}
+		}
+	}()
+	return nil
+}
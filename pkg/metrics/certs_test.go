@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func writeKeyPair(t *testing.T, certFile, keyFile string, notAfter time.Time) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotAfter:     notAfter,
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedExpiry(t *testing.T, r *certReloader) time.Time {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Leaf.NotAfter
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	writeKeyPair(t, certFile, keyFile, first)
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if expiry := servedExpiry(t, r); !expiry.Equal(first) {
+		t.Errorf("expected certificate expiring at %v, got %v", first, expiry)
+	}
+	if expiry := testutil.ToFloat64(certExpiry); expiry != float64(first.Unix()) {
+		t.Errorf("expected expiry gauge %v, got %v", first.Unix(), expiry)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := r.watch(stopCh); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// an invalid key pair is not served
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err == nil {
+		t.Errorf("expected an error reloading an invalid certificate")
+	}
+	if expiry := servedExpiry(t, r); !expiry.Equal(first) {
+		t.Errorf("expected certificate expiring at %v to still be served, got %v", first, expiry)
+	}
+
+	second := first.Add(24 * time.Hour)
+	writeKeyPair(t, certFile, keyFile, second)
+	if err := wait.PollImmediate(50*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return servedExpiry(t, r).Equal(second), nil
+	}); err != nil {
+		t.Fatalf("rotated certificate expiring at %v was not reloaded, got %v", second, servedExpiry(t, r))
+	}
+	if expiry := testutil.ToFloat64(certExpiry); expiry != float64(second.Unix()) {
+		t.Errorf("expected expiry gauge %v, got %v", second.Unix(), expiry)
+	}
+}
@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	driverName = "csi.sharedresource.openshift.io"
+
+	// the volume attributes naming the share an inline CSI volume of the driver mounts
+	sharedSecretAttribute    = "sharedSecret"
+	sharedConfigMapAttribute = "sharedConfigMap"
+
+	consumerPodsName       = sharesSubsystem + separator + "consumer_pods"
+	consumerNamespacesName = sharesSubsystem + separator + "consumer_namespaces"
+)
+
+var (
+	consumerPodsDesc = prometheus.NewDesc(
+		consumerPodsName,
+		"Counts the running or pending pods mounting a share through an inline CSI volume",
+		[]string{"kind", "share"},
+		nil,
+	)
+
+	consumerNamespacesDesc = prometheus.NewDesc(
+		consumerNamespacesName,
+		"Counts the namespaces with running or pending pods mounting a share through an inline CSI volume",
+		[]string{"kind", "share"},
+		nil,
+	)
+)
+
+func (sc *sharesCollector) podEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				sc.setPod(pod)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				sc.setPod(pod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				sc.deletePod(pod.Namespace + "/" + pod.Name)
+			}
+		},
+	}
+}
+
+// TransformConsumerPod strips a pod down to what mountedShares reads, its name, phase and the inline CSI volumes of
+// the driver, so that the informer counting share consumers does not cache the full pods of every namespace. It is
+// meant for cache.SharedInformer.SetTransform; other objects, such as tombstones, are returned unchanged.
+func TransformConsumerPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	stripped := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Status: corev1.PodStatus{Phase: pod.Status.Phase},
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.CSI != nil && volume.CSI.Driver == driverName {
+			stripped.Spec.Volumes = append(stripped.Spec.Volumes, volume)
+		}
+	}
+	return stripped, nil
+}
+
+// mountedShares returns the shares, keyed like sharesCollector.shares, that the inline CSI volumes of pod mount.
+// Pods that have terminated no longer hold their volumes and mount nothing.
+func mountedShares(pod *corev1.Pod) []string {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil
+	}
+	shares := []string{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.CSI == nil || volume.CSI.Driver != driverName {
+			continue
+		}
+		if name := volume.CSI.VolumeAttributes[sharedSecretAttribute]; len(name) > 0 {
+			shares = append(shares, secret+"/"+name)
+		}
+		if name := volume.CSI.VolumeAttributes[sharedConfigMapAttribute]; len(name) > 0 {
+			shares = append(shares, cm+"/"+name)
+		}
+	}
+	return shares
+}
+
+func (sc *sharesCollector) setPod(pod *corev1.Pod) {
+	key := pod.Namespace + "/" + pod.Name
+	shares := mountedShares(pod)
+	if len(shares) == 0 {
+		sc.deletePod(key)
+		return
+	}
+	sc.mountCountLock.Lock()
+	defer sc.mountCountLock.Unlock()
+	if sc.podShares == nil {
+		sc.podShares = map[string]podShares{}
+		sc.consumers = map[string]map[string]int{}
+	}
+	if old, ok := sc.podShares[key]; ok {
+		sc.countConsumer(old, -1)
+	}
+	mounted := podShares{namespace: pod.Namespace, shares: shares}
+	sc.podShares[key] = mounted
+	sc.countConsumer(mounted, 1)
+}
+
+func (sc *sharesCollector) deletePod(key string) {
+	sc.mountCountLock.Lock()
+	defer sc.mountCountLock.Unlock()
+	if old, ok := sc.podShares[key]; ok {
+		sc.countConsumer(old, -1)
+		delete(sc.podShares, key)
+	}
+}
+
+// countConsumer adds delta to the pods of the namespace of mounted consuming each of its shares. It is called
+// with mountCountLock held.
+func (sc *sharesCollector) countConsumer(mounted podShares, delta int) {
+	seen := map[string]bool{}
+	for _, share := range mounted.shares {
+		// a pod mounting a share twice is still a single consumer
+		if seen[share] {
+			continue
+		}
+		seen[share] = true
+		namespaces := sc.consumers[share]
+		if namespaces == nil {
+			namespaces = map[string]int{}
+			sc.consumers[share] = namespaces
+		}
+		namespaces[mounted.namespace] += delta
+		if namespaces[mounted.namespace] <= 0 {
+			delete(namespaces, mounted.namespace)
+		}
+		if len(namespaces) == 0 {
+			delete(sc.consumers, share)
+		}
+	}
+}
+
+// collectConsumers exports the consumers of the known shares, keyed by kind and name, and of the shares pods
+// mount without them existing, under the same cap as infoName. It is called with sharesLock held.
+func (sc *sharesCollector) collectConsumers(ch chan<- prometheus.Metric) {
+	sc.mountCountLock.Lock()
+	defer sc.mountCountLock.Unlock()
+
+	keys := make([]string, 0, len(sc.shares))
+	for key := range sc.shares {
+		keys = append(keys, key)
+	}
+	for key := range sc.consumers {
+		if _, ok := sc.shares[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > maxShareInfoSeries {
+		keys = keys[:maxShareInfoSeries]
+	}
+	for _, key := range keys {
+		kind, name, _ := strings.Cut(key, "/")
+		pods := 0
+		for _, count := range sc.consumers[key] {
+			pods += count
+		}
+		ch <- prometheus.MustNewConstMetric(
+			consumerPodsDesc,
+			prometheus.GaugeValue,
+			float64(pods),
+			kind, name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			consumerNamespacesDesc,
+			prometheus.GaugeValue,
+			float64(len(sc.consumers[key])),
+			kind, name,
+		)
+	}
+}
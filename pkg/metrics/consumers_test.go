@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	v1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func consumerPod(namespace, name string, attributes ...map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	for _, attrs := range attributes {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: "share",
+			VolumeSource: corev1.VolumeSource{
+				CSI: &corev1.CSIVolumeSource{Driver: driverName, VolumeAttributes: attrs},
+			},
+		})
+	}
+	return pod
+}
+
+func TestConsumerMetrics(t *testing.T) {
+	sc = sharesCollector{isCreated: true}
+	sc.shareEventHandler(secret).OnAdd(&v1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "unused"},
+		Spec:       v1alpha1.SharedSecretSpec{SecretRef: v1alpha1.SharedSecretReference{Name: "s", Namespace: "ns"}},
+	}, true)
+	sc.shareEventHandler(secret).OnAdd(&v1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret"},
+		Spec:       v1alpha1.SharedSecretSpec{SecretRef: v1alpha1.SharedSecretReference{Name: "pull-secret", Namespace: "openshift-config"}},
+	}, true)
+
+	handler := sc.podEventHandler()
+	pullSecret := map[string]string{sharedSecretAttribute: "pull-secret"}
+	trustedCA := map[string]string{sharedConfigMapAttribute: "trusted-ca"}
+	handler.OnAdd(consumerPod("builds-a", "build-1", pullSecret, trustedCA), true)
+	handler.OnAdd(consumerPod("builds-a", "build-2", pullSecret, pullSecret), true)
+	handler.OnAdd(consumerPod("builds-b", "build-3", pullSecret), true)
+	handler.OnAdd(consumerPod("builds-b", "unrelated"), true)
+	// another driver's volume is not a consumer
+	other := consumerPod("builds-b", "other-driver", pullSecret)
+	other.Spec.Volumes[1].CSI.Driver = "other.csi.example.com"
+	handler.OnAdd(other, true)
+	// completed pods, and deleted ones, no longer consume their shares
+	completed := consumerPod("builds-c", "build-4", pullSecret)
+	handler.OnAdd(completed, true)
+	completed = completed.DeepCopy()
+	completed.Status.Phase = corev1.PodSucceeded
+	handler.OnUpdate(nil, completed)
+	deleted := consumerPod("builds-c", "build-5", trustedCA)
+	handler.OnAdd(deleted, true)
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "builds-c/build-5", Obj: deleted})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&sc)
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.PanicOnError})
+	rw := &fakeResponseWriter{header: http.Header{}}
+	h.ServeHTTP(rw, &http.Request{})
+	respStr := rw.String()
+
+	for _, s := range []string{
+		`openshift_csi_share_consumer_pods{kind="secret",share="pull-secret"} 3`,
+		`openshift_csi_share_consumer_namespaces{kind="secret",share="pull-secret"} 2`,
+		`openshift_csi_share_consumer_pods{kind="secret",share="unused"} 0`,
+		`openshift_csi_share_consumer_namespaces{kind="secret",share="unused"} 0`,
+		// mounted, though no SharedConfigMap of that name exists
+		`openshift_csi_share_consumer_pods{kind="configmap",share="trusted-ca"} 1`,
+		`openshift_csi_share_consumer_namespaces{kind="configmap",share="trusted-ca"} 1`,
+	} {
+		if !strings.Contains(respStr, s) {
+			t.Errorf("expected string %s did not appear in %s", s, respStr)
+		}
+	}
+}
+
+func TestTransformConsumerPod(t *testing.T) {
+	pod := consumerPod("builds-a", "build-1", map[string]string{sharedSecretAttribute: "pull-secret"})
+	pod.Labels = map[string]string{"app": "build"}
+	pod.Spec.Containers = []corev1.Container{{Name: "build", Image: "builder", Env: []corev1.EnvVar{{Name: "TOKEN", Value: "secret"}}}}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "build", Ready: true}}
+	pod.Status.Phase = corev1.PodPending
+
+	obj, err := TransformConsumerPod(pod)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	stripped := obj.(*corev1.Pod)
+	if len(stripped.Labels) > 0 || len(stripped.Spec.Containers) > 0 || len(stripped.Status.ContainerStatuses) > 0 {
+		t.Errorf("expected only the name, phase and share volumes to be kept, got %v", stripped)
+	}
+	if stripped.Namespace != pod.Namespace || stripped.Name != pod.Name || stripped.Status.Phase != corev1.PodPending {
+		t.Errorf("expected the name and phase of %s/%s to be kept, got %v", pod.Namespace, pod.Name, stripped)
+	}
+	if shares := mountedShares(stripped); len(shares) != 1 || shares[0] != secret+"/pull-secret" {
+		t.Errorf("expected the stripped pod to mount %s/pull-secret, got %v", secret, shares)
+	}
+
+	tombstone := cache.DeletedFinalStateUnknown{Key: "builds-a/build-1", Obj: pod}
+	if obj, err := TransformConsumerPod(tombstone); err != nil || obj != tombstone {
+		t.Errorf("expected the tombstone to be returned unchanged, got %v, %v", obj, err)
+	}
+}
@@ -1,14 +1,18 @@
 package metrics
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/blang/semver"
-	sharev1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	shareinformers "github.com/openshift/client-go/sharedresource/informers/externalversions/sharedresource/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
 
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/klog/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
@@ -19,8 +23,16 @@ const (
 	cm     = "configmap"
 	secret = "secret"
 
-	cmCountName     = sharesSubsystem + separator + cm
-	secretCountName = sharesSubsystem + separator + secret
+	cmCountName          = sharesSubsystem + separator + cm
+	secretCountName      = sharesSubsystem + separator + secret
+	orphanedCountName    = sharesSubsystem + separator + "orphaned"
+	infoName             = sharesSubsystem + separator + "info"
+	infoOmittedName      = sharesSubsystem + separator + "info_omitted"
+	namespaceCountName   = sharesSubsystem + separator + "backing_namespace"
+	otherNamespacesLabel = "_other"
+
+	// backingResourceAvailableCondition is set on shares by the status controller
+	backingResourceAvailableCondition = "BackingResourceAvailable"
 
 	MetricsPort = 6000
 )
@@ -40,22 +52,88 @@ var (
 		nil,
 	)
 
+	orphanedCountDesc = prometheus.NewDesc(
+		orphanedCountName,
+		"Counts shares whose backing Secret or ConfigMap, or its namespace, no longer exists",
+		[]string{"kind"},
+		nil,
+	)
+
+	infoDesc = prometheus.NewDesc(
+		infoName,
+		"Describes a share with the namespace and name of its backing Secret or ConfigMap",
+		[]string{"kind", "share", "namespace", "name"},
+		nil,
+	)
+
+	infoOmittedDesc = prometheus.NewDesc(
+		infoOmittedName,
+		"Counts shares left out of "+infoName+" to bound its cardinality",
+		[]string{},
+		nil,
+	)
+
+	namespaceCountDesc = prometheus.NewDesc(
+		namespaceCountName,
+		"Counts shares by the namespace of their backing Secret or ConfigMap; namespaces beyond the cardinality cap are counted under "+otherNamespacesLabel,
+		[]string{"kind", "namespace"},
+		nil,
+	)
+
 	sc = sharesCollector{}
+
+	// maxShareInfoSeries caps the series of infoName, maxBackingNamespaceSeries those of namespaceCountName per kind
+	maxShareInfoSeries        = 500
+	maxBackingNamespaceSeries = 100
 )
 
+// share is what the collector keeps of a SharedSecret or SharedConfigMap.
+type share struct {
+	kind             string
+	name             string
+	backingNamespace string
+	backingName      string
+	orphaned         bool
+}
+
+// sharesCollector exports the shares it is told about by the informer event handlers, so that a scrape does not
+// list them all.
 type sharesCollector struct {
-	sharedSecretLister    sharev1alpha1.SharedSecretLister
-	sharedConfigMapLister sharev1alpha1.SharedConfigMapLister
-	isCreated             bool
-	createLock            sync.Mutex
+	isCreated  bool
+	createLock sync.Mutex
 
+	// sharesLock guards shares and namespaceCounts
+	sharesLock sync.Mutex
+	// shares are keyed by kind and name
+	shares map[string]share
+	// namespaceCounts counts shares by kind and backing namespace
+	namespaceCounts map[string]map[string]int
+
+	// mountCountLock guards podShares and consumers
 	mountCountLock sync.Mutex
+	// podShares are the shares mounted by pods, keyed by namespace and name
+	podShares map[string]podShares
+	// consumers counts the pods mounting a share, keyed like shares, by namespace
+	consumers map[string]map[string]int
 }
 
-func InitializeShareCollector(sl sharev1alpha1.SharedSecretLister, cml sharev1alpha1.SharedConfigMapLister) error {
+// podShares are the shares mounted by the inline CSI volumes of a pod in namespace.
+type podShares struct {
+	namespace string
+	shares    []string
+}
+
+func InitializeShareCollector(secretInformer shareinformers.SharedSecretInformer, cmInformer shareinformers.SharedConfigMapInformer, podInformer corev1informers.PodInformer) error {
 	if !sc.isCreated {
-		sc.sharedSecretLister = sl
-		sc.sharedConfigMapLister = cml
+		if _, err := podInformer.Informer().AddEventHandler(sc.podEventHandler()); err != nil {
+			return err
+		}
+		if _, err := secretInformer.Informer().AddEventHandler(sc.shareEventHandler(secret)); err != nil {
+			return err
+		}
+		if _, err := cmInformer.Informer().AddEventHandler(sc.shareEventHandler(cm)); err != nil {
+			return err
+		}
 		err := prometheus.Register(&sc)
 		if err != nil {
 			return err
@@ -90,35 +168,207 @@ func (sc *sharesCollector) FQName() string {
 	return sharesSubsystem
 }
 
+func (sc *sharesCollector) shareEventHandler(kind string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if s, ok := toShare(kind, obj); ok {
+				sc.setShare(s)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if s, ok := toShare(kind, obj); ok {
+				sc.setShare(s)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if s, ok := toShare(kind, obj); ok {
+				sc.deleteShare(s)
+			}
+		},
+	}
+}
+
+func toShare(kind string, obj interface{}) (share, bool) {
+	switch o := obj.(type) {
+	case *sharev1alpha1.SharedSecret:
+		return share{
+			kind:             kind,
+			name:             o.Name,
+			backingNamespace: o.Spec.SecretRef.Namespace,
+			backingName:      o.Spec.SecretRef.Name,
+			orphaned:         isOrphaned(o.Status.Conditions),
+		}, true
+	case *sharev1alpha1.SharedConfigMap:
+		return share{
+			kind:             kind,
+			name:             o.Name,
+			backingNamespace: o.Spec.ConfigMapRef.Namespace,
+			backingName:      o.Spec.ConfigMapRef.Name,
+			orphaned:         isOrphaned(o.Status.Conditions),
+		}, true
+	}
+	return share{}, false
+}
+
+func (sc *sharesCollector) setShare(s share) {
+	sc.sharesLock.Lock()
+	defer sc.sharesLock.Unlock()
+	if sc.shares == nil {
+		sc.shares = map[string]share{}
+		sc.namespaceCounts = map[string]map[string]int{}
+	}
+	key := s.kind + "/" + s.name
+	if old, ok := sc.shares[key]; ok {
+		sc.countNamespace(old, -1)
+	}
+	sc.shares[key] = s
+	sc.countNamespace(s, 1)
+}
+
+func (sc *sharesCollector) deleteShare(s share) {
+	sc.sharesLock.Lock()
+	defer sc.sharesLock.Unlock()
+	key := s.kind + "/" + s.name
+	if old, ok := sc.shares[key]; ok {
+		sc.countNamespace(old, -1)
+		delete(sc.shares, key)
+	}
+}
+
+// countNamespace adds delta to the count of the backing namespace of s. It is called with sharesLock held.
+func (sc *sharesCollector) countNamespace(s share, delta int) {
+	counts := sc.namespaceCounts[s.kind]
+	if counts == nil {
+		counts = map[string]int{}
+		sc.namespaceCounts[s.kind] = counts
+	}
+	counts[s.backingNamespace] += delta
+	if counts[s.backingNamespace] <= 0 {
+		delete(counts, s.backingNamespace)
+	}
+}
+
 func (sc *sharesCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- secretCountDesc
 	ch <- cmCountDesc
+	ch <- orphanedCountDesc
+	ch <- infoDesc
+	ch <- infoOmittedDesc
+	ch <- namespaceCountDesc
+	ch <- consumerPodsDesc
+	ch <- consumerNamespacesDesc
 }
 
 func (sc *sharesCollector) Collect(ch chan<- prometheus.Metric) {
-	sharedSecrets, err := sc.sharedSecretLister.List(labels.Everything())
-	if err != nil {
-		klog.V(4).Error(err, "Error while collecting shared Secrets for metrics")
-		return
-	}
-	sharedConfigMaps, err := sc.sharedConfigMapLister.List(labels.Everything())
-	if err != nil {
-		klog.V(4).Error(err, "Error while collecting shared ConfigMaps for metrics")
-		return
-	}
+	sc.sharesLock.Lock()
+	defer sc.sharesLock.Unlock()
 
-	secretCounts := len(sharedSecrets)
-	cmCounts := len(sharedConfigMaps)
+	counts := map[string]int{}
+	orphaned := map[string]int{}
+	keys := make([]string, 0, len(sc.shares))
+	for key, s := range sc.shares {
+		counts[s.kind]++
+		if s.orphaned {
+			orphaned[s.kind]++
+		}
+		keys = append(keys, key)
+	}
 
 	ch <- prometheus.MustNewConstMetric(
 		secretCountDesc,
 		prometheus.GaugeValue,
-		float64(secretCounts),
+		float64(counts[secret]),
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		cmCountDesc,
 		prometheus.GaugeValue,
-		float64(cmCounts),
+		float64(counts[cm]),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		orphanedCountDesc,
+		prometheus.GaugeValue,
+		float64(orphaned[secret]),
+		secret,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		orphanedCountDesc,
+		prometheus.GaugeValue,
+		float64(orphaned[cm]),
+		cm,
 	)
+
+	// the series kept under the cap are the first ones in name order, so that they do not change between scrapes
+	sort.Strings(keys)
+	omitted := 0
+	for i, key := range keys {
+		if i >= maxShareInfoSeries {
+			omitted = len(keys) - i
+			break
+		}
+		s := sc.shares[key]
+		ch <- prometheus.MustNewConstMetric(
+			infoDesc,
+			prometheus.GaugeValue,
+			1,
+			s.kind, s.name, s.backingNamespace, s.backingName,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		infoOmittedDesc,
+		prometheus.GaugeValue,
+		float64(omitted),
+	)
+
+	for _, kind := range []string{secret, cm} {
+		collectNamespaceCounts(ch, kind, sc.namespaceCounts[kind])
+	}
+
+	sc.collectConsumers(ch)
+}
+
+// collectNamespaceCounts exports counts, keyed by namespace, keeping the namespaces with the most shares under
+// the cap and adding up the others under otherNamespacesLabel.
+func collectNamespaceCounts(ch chan<- prometheus.Metric, kind string, counts map[string]int) {
+	namespaces := make([]string, 0, len(counts))
+	for ns := range counts {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		if counts[namespaces[i]] != counts[namespaces[j]] {
+			return counts[namespaces[i]] > counts[namespaces[j]]
+		}
+		return namespaces[i] < namespaces[j]
+	})
+	other := 0
+	for i, ns := range namespaces {
+		if i >= maxBackingNamespaceSeries {
+			other += counts[ns]
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			namespaceCountDesc,
+			prometheus.GaugeValue,
+			float64(counts[ns]),
+			kind, ns,
+		)
+	}
+	if other > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			namespaceCountDesc,
+			prometheus.GaugeValue,
+			float64(other),
+			kind, otherNamespacesLabel,
+		)
+	}
+}
+
+// isOrphaned is true when the status controller found the backing resource of a share to be missing.
+func isOrphaned(conditions []metav1.Condition) bool {
+	return meta.IsStatusConditionFalse(conditions, backingResourceAvailableCondition)
 }
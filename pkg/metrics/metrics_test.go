@@ -10,7 +10,9 @@ import (
 	sharev1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
 )
 
 type fakeConfigMapShareLister struct {
@@ -63,6 +65,21 @@ func (f *fakeResponseWriter) WriteHeader(statusCode int) {
 	f.statusCode = statusCode
 }
 
+// resetShareCollector replaces sc with a collector told about the shares of the listers by its event handlers.
+func resetShareCollector(secretLister sharev1alpha1.SharedSecretLister, cmLister sharev1alpha1.SharedConfigMapLister) {
+	sc = sharesCollector{
+		isCreated: true,
+	}
+	secrets, _ := secretLister.List(labels.Everything())
+	for _, share := range secrets {
+		sc.shareEventHandler(secret).OnAdd(share, true)
+	}
+	cms, _ := cmLister.List(labels.Everything())
+	for _, share := range cms {
+		sc.shareEventHandler(cm).OnAdd(share, true)
+	}
+}
+
 func TestMetrics(t *testing.T) {
 	for _, test := range []struct {
 		name         string
@@ -83,6 +100,7 @@ func TestMetrics(t *testing.T) {
 			secretLister: &fakeSecretShareLister{
 				secretShares: []*v1alpha1.SharedSecret{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name"},
 						Spec: v1alpha1.SharedSecretSpec{
 							SecretRef: v1alpha1.SharedSecretReference{
 								Name:      "secret-name",
@@ -95,6 +113,7 @@ func TestMetrics(t *testing.T) {
 			cmLister: &fakeConfigMapShareLister{
 				cmShares: []*v1alpha1.SharedConfigMap{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name"},
 						Spec: v1alpha1.SharedConfigMapSpec{
 							ConfigMapRef: v1alpha1.SharedConfigMapReference{
 								Name:      "config-map-name",
@@ -118,6 +137,7 @@ func TestMetrics(t *testing.T) {
 			secretLister: &fakeSecretShareLister{
 				secretShares: []*v1alpha1.SharedSecret{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name"},
 						Spec: v1alpha1.SharedSecretSpec{
 							SecretRef: v1alpha1.SharedSecretReference{
 								Name:      "secret-name",
@@ -126,6 +146,7 @@ func TestMetrics(t *testing.T) {
 						},
 					},
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name-2"},
 						Spec: v1alpha1.SharedSecretSpec{
 							SecretRef: v1alpha1.SharedSecretReference{
 								Name:      "secret-name-2",
@@ -155,6 +176,7 @@ func TestMetrics(t *testing.T) {
 			cmLister: &fakeConfigMapShareLister{
 				cmShares: []*v1alpha1.SharedConfigMap{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name"},
 						Spec: v1alpha1.SharedConfigMapSpec{
 							ConfigMapRef: v1alpha1.SharedConfigMapReference{
 								Name:      "config-map-name",
@@ -163,6 +185,7 @@ func TestMetrics(t *testing.T) {
 						},
 					},
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name-2"},
 						Spec: v1alpha1.SharedConfigMapSpec{
 							ConfigMapRef: v1alpha1.SharedConfigMapReference{
 								Name:      "config-map-name-2",
@@ -173,14 +196,64 @@ func TestMetrics(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "One orphaned secret, one config map",
+			expected: []string{
+				"# HELP openshift_csi_share_orphaned Counts shares whose backing Secret or ConfigMap, or its namespace, no longer exists",
+				"# TYPE openshift_csi_share_orphaned gauge",
+				`openshift_csi_share_orphaned{kind="configmap"} 0`,
+				`openshift_csi_share_orphaned{kind="secret"} 1`,
+				"openshift_csi_share_secret 2",
+			},
+			secretLister: &fakeSecretShareLister{
+				secretShares: []*v1alpha1.SharedSecret{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name"},
+						Spec: v1alpha1.SharedSecretSpec{
+							SecretRef: v1alpha1.SharedSecretReference{
+								Name:      "secret-name",
+								Namespace: "namespace-1",
+							},
+						},
+						Status: v1alpha1.SharedSecretStatus{
+							Conditions: []metav1.Condition{
+								{
+									Type:   "BackingResourceAvailable",
+									Status: metav1.ConditionFalse,
+									Reason: "NotFound",
+								},
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name-2"},
+						Spec: v1alpha1.SharedSecretSpec{
+							SecretRef: v1alpha1.SharedSecretReference{
+								Name:      "secret-name-2",
+								Namespace: "namespace-1",
+							},
+						},
+					},
+				},
+			},
+			cmLister: &fakeConfigMapShareLister{
+				cmShares: []*v1alpha1.SharedConfigMap{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name"},
+						Spec: v1alpha1.SharedConfigMapSpec{
+							ConfigMapRef: v1alpha1.SharedConfigMapReference{
+								Name:      "config-map-name",
+								Namespace: "namespace-2",
+							},
+						},
+					},
+				},
+			},
+		},
 	} {
 
 		registry := prometheus.NewRegistry()
-		sc = sharesCollector{
-			sharedSecretLister:    test.secretLister,
-			sharedConfigMapLister: test.cmLister,
-			isCreated:             true,
-		}
+		resetShareCollector(test.secretLister, test.cmLister)
 
 		registry.MustRegister(&sc)
 
@@ -197,3 +270,61 @@ func TestMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestShareInfoMetrics(t *testing.T) {
+	defer func(info, namespaces int) {
+		maxShareInfoSeries, maxBackingNamespaceSeries = info, namespaces
+	}(maxShareInfoSeries, maxBackingNamespaceSeries)
+	maxShareInfoSeries, maxBackingNamespaceSeries = 3, 2
+
+	sharedSecret := func(name, namespace string) *v1alpha1.SharedSecret {
+		return &v1alpha1.SharedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.SharedSecretSpec{SecretRef: v1alpha1.SharedSecretReference{Name: name, Namespace: namespace}},
+		}
+	}
+	sc = sharesCollector{isCreated: true}
+	handler := sc.shareEventHandler(secret)
+	handler.OnAdd(sharedSecret("a", "ns-1"), true)
+	handler.OnAdd(sharedSecret("b", "ns-1"), true)
+	handler.OnAdd(sharedSecret("c", "ns-2"), true)
+	handler.OnAdd(sharedSecret("d", "ns-3"), true)
+	handler.OnAdd(sharedSecret("e", "ns-4"), true)
+	handler.OnAdd(sharedSecret("f", "ns-5"), true)
+	// moved to another backing namespace, then deleted through a tombstone
+	handler.OnUpdate(sharedSecret("c", "ns-2"), sharedSecret("c", "ns-1"))
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "e", Obj: sharedSecret("e", "ns-4")})
+	cmHandler := sc.shareEventHandler(cm)
+	cmHandler.OnAdd(&v1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca"},
+		Spec:       v1alpha1.SharedConfigMapSpec{ConfigMapRef: v1alpha1.SharedConfigMapReference{Name: "ca", Namespace: "ns-1"}},
+	}, true)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&sc)
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.PanicOnError})
+	rw := &fakeResponseWriter{header: http.Header{}}
+	h.ServeHTTP(rw, &http.Request{})
+	respStr := rw.String()
+
+	for _, s := range []string{
+		"openshift_csi_share_secret 5",
+		`openshift_csi_share_info{kind="configmap",name="ca",namespace="ns-1",share="trusted-ca"} 1`,
+		`openshift_csi_share_info{kind="secret",name="a",namespace="ns-1",share="a"} 1`,
+		`openshift_csi_share_info{kind="secret",name="b",namespace="ns-1",share="b"} 1`,
+		"openshift_csi_share_info_omitted 3",
+		`openshift_csi_share_backing_namespace{kind="secret",namespace="ns-1"} 3`,
+		`openshift_csi_share_backing_namespace{kind="secret",namespace="ns-3"} 1`,
+		`openshift_csi_share_backing_namespace{kind="secret",namespace="_other"} 1`,
+		`openshift_csi_share_backing_namespace{kind="configmap",namespace="ns-1"} 1`,
+	} {
+		if !strings.Contains(respStr, s) {
+			t.Errorf("expected string %s did not appear in %s", s, respStr)
+		}
+	}
+	for _, s := range []string{`share="c"`, `namespace="ns-2"`, `namespace="ns-4"`, `namespace="ns-5"`} {
+		if strings.Contains(respStr, s) {
+			t.Errorf("unexpected string %s in %s", s, respStr)
+		}
+	}
+}
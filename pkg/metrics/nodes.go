@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	driverNodeRegisteredName = sharesSubsystem + separator + "driver_node_registered"
+	driverNodeRestartsName   = sharesSubsystem + separator + "driver_node_restarts"
+)
+
+var (
+	driverNodeRegistered = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: driverNodeRegisteredName,
+		Help: "Whether the CSI shared resource driver is registered in the CSINode of a node running a driver pod",
+	}, []string{"node"})
+
+	driverNodeRestarts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: driverNodeRestartsName,
+		Help: "Container restarts of the CSI shared resource driver pod running on a node",
+	}, []string{"node"})
+
+	driverNodesLock sync.Mutex
+	driverNodes     = map[string]bool{}
+)
+
+func init() {
+	prometheus.MustRegister(driverNodeRegistered, driverNodeRestarts)
+}
+
+// DriverNodeState is the state of the driver pod running on a node.
+type DriverNodeState struct {
+	Registered bool
+	Restarts   int32
+}
+
+// SetDriverNodes replaces the per node driver gauges with nodes, keyed by node name. Nodes that are no longer
+// running a driver pod are dropped from the gauges.
+func SetDriverNodes(nodes map[string]DriverNodeState) {
+	driverNodesLock.Lock()
+	defer driverNodesLock.Unlock()
+	for node := range driverNodes {
+		if _, ok := nodes[node]; !ok {
+			driverNodeRegistered.DeleteLabelValues(node)
+			driverNodeRestarts.DeleteLabelValues(node)
+			delete(driverNodes, node)
+		}
+	}
+	for node, state := range nodes {
+		registered := 0.0
+		if state.Registered {
+			registered = 1
+		}
+		driverNodeRegistered.WithLabelValues(node).Set(registered)
+		driverNodeRestarts.WithLabelValues(node).Set(float64(state.Restarts))
+		driverNodes[node] = true
+	}
+}
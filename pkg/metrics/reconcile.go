@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	operatorSubsystem = sharesSubsystem + separator + "operator"
+
+	syncDurationName     = operatorSubsystem + separator + "sync_duration_seconds"
+	syncErrorsName       = operatorSubsystem + separator + "sync_errors_total"
+	objectsRecreatedName = operatorSubsystem + separator + "objects_recreated_total"
+	applyErrorsName      = operatorSubsystem + separator + "apply_errors_total"
+)
+
+var (
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    syncDurationName,
+		Help:    "Duration of the syncs of the operator controllers",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"controller"})
+
+	syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: syncErrorsName,
+		Help: "Counts the syncs of the operator controllers that failed",
+	}, []string{"controller"})
+
+	objectsRecreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: objectsRecreatedName,
+		Help: "Counts the objects the operator controllers created because they were missing",
+	}, []string{"controller", "kind"})
+
+	applyErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: applyErrorsName,
+		Help: "Counts the failed attempts of the operator controllers to create, update or delete an object",
+	}, []string{"controller", "kind"})
+)
+
+func init() {
+	prometheus.MustRegister(syncDuration, syncErrors, objectsRecreated, applyErrors)
+}
+
+// InstrumentSync records the duration and the errors of sync under controller.
+func InstrumentSync(controller string, sync factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, syncContext factory.SyncContext) error {
+		start := time.Now()
+		err := sync(ctx, syncContext)
+		syncDuration.WithLabelValues(controller).Observe(time.Since(start).Seconds())
+		if err != nil {
+			syncErrors.WithLabelValues(controller).Inc()
+		}
+		return err
+	}
+}
+
+// RecordObjectRecreated counts an object of kind that controller restored without going through resourceapply.
+func RecordObjectRecreated(controller, kind string) {
+	objectsRecreated.WithLabelValues(controller, kind).Inc()
+}
+
+// InstrumentRecorder counts, under controller, the objects created and the failed object changes that the
+// resourceapply functions report through the events of recorder, as they are not returned to the caller.
+func InstrumentRecorder(controller string, recorder events.Recorder) events.Recorder {
+	return &instrumentedRecorder{Recorder: recorder, controller: controller}
+}
+
+type instrumentedRecorder struct {
+	events.Recorder
+	controller string
+}
+
+// observe counts the <Kind>Created and <Kind>{Create,Update,Delete}Failed events of resourceapply.
+func (r *instrumentedRecorder) observe(reason string) {
+	for _, suffix := range []string{"CreateFailed", "UpdateFailed", "DeleteFailed"} {
+		if kind := strings.TrimSuffix(reason, suffix); kind != reason && len(kind) > 0 {
+			applyErrors.WithLabelValues(r.controller, kind).Inc()
+			return
+		}
+	}
+	if kind := strings.TrimSuffix(reason, "Created"); kind != reason && len(kind) > 0 {
+		RecordObjectRecreated(r.controller, kind)
+	}
+}
+
+func (r *instrumentedRecorder) Event(reason, message string) {
+	r.observe(reason)
+	r.Recorder.Event(reason, message)
+}
+
+func (r *instrumentedRecorder) Eventf(reason, messageFmt string, args ...interface{}) {
+	r.Event(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *instrumentedRecorder) Warning(reason, message string) {
+	r.observe(reason)
+	r.Recorder.Warning(reason, message)
+}
+
+func (r *instrumentedRecorder) Warningf(reason, messageFmt string, args ...interface{}) {
+	r.Warning(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *instrumentedRecorder) ForComponent(componentName string) events.Recorder {
+	return InstrumentRecorder(r.controller, r.Recorder.ForComponent(componentName))
+}
+
+func (r *instrumentedRecorder) WithComponentSuffix(componentNameSuffix string) events.Recorder {
+	return InstrumentRecorder(r.controller, r.Recorder.WithComponentSuffix(componentNameSuffix))
+}
+
+func (r *instrumentedRecorder) WithContext(ctx context.Context) events.Recorder {
+	return InstrumentRecorder(r.controller, r.Recorder.WithContext(ctx))
+}
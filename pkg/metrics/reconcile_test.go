@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestReconcileMetricsRegistered checks that the reconciliation metrics are served without waiting for the share
+// collector, whose informers may never sync.
+func TestReconcileMetricsRegistered(t *testing.T) {
+	for _, collector := range []prometheus.Collector{syncDuration, syncErrors, objectsRecreated, applyErrors} {
+		err := prometheus.Register(collector)
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			t.Errorf("expected the collector to be registered already, got %v", err)
+		}
+	}
+}
+
+func TestInstrumentSync(t *testing.T) {
+	syncErrors.Reset()
+	for _, test := range []struct {
+		name         string
+		controller   string
+		err          error
+		expectErrors float64
+	}{
+		{
+			name:       "successful sync",
+			controller: "TestSuccessfulController",
+		},
+		{
+			name:         "failed sync",
+			controller:   "TestFailedController",
+			err:          fmt.Errorf("apply failed"),
+			expectErrors: 1,
+		},
+	} {
+		sync := InstrumentSync(test.controller, func(context.Context, factory.SyncContext) error {
+			return test.err
+		})
+		if err := sync(context.TODO(), factory.NewSyncContext(test.controller, events.NewInMemoryRecorder(test.controller))); err != test.err {
+			t.Errorf("testcase %s: expected error %v, got %v", test.name, test.err, err)
+		}
+		if count := testutil.CollectAndCount(syncDuration, syncDurationName); count < 1 {
+			t.Errorf("testcase %s: expected sync durations to be observed", test.name)
+		}
+		if errors := testutil.ToFloat64(syncErrors.WithLabelValues(test.controller)); errors != test.expectErrors {
+			t.Errorf("testcase %s: expected %v sync errors, got %v", test.name, test.expectErrors, errors)
+		}
+	}
+}
+
+func TestInstrumentRecorder(t *testing.T) {
+	objectsRecreated.Reset()
+	applyErrors.Reset()
+	controller := "TestRecorderController"
+	recorder := InstrumentRecorder(controller, events.NewInMemoryRecorder(controller)).WithComponentSuffix("test")
+
+	recorder.Eventf("ConfigMapCreated", "Created ConfigMap/%s -n %s because it was missing", "config", "ns")
+	recorder.Eventf("ConfigMapUpdated", "Updated ConfigMap/%s -n %s", "config", "ns")
+	recorder.Warningf("DeploymentUpdateFailed", "Failed to update Deployment/%s -n %s", "webhook", "ns")
+	recorder.Event("Created", "no kind")
+	RecordObjectRecreated(controller, "NamespaceLabels")
+
+	for _, test := range []struct {
+		name     string
+		counter  float64
+		expected float64
+	}{
+		{"ConfigMap recreated", testutil.ToFloat64(objectsRecreated.WithLabelValues(controller, "ConfigMap")), 1},
+		{"NamespaceLabels recreated", testutil.ToFloat64(objectsRecreated.WithLabelValues(controller, "NamespaceLabels")), 1},
+		{"Deployment recreated", testutil.ToFloat64(objectsRecreated.WithLabelValues(controller, "Deployment")), 0},
+		{"Deployment apply error", testutil.ToFloat64(applyErrors.WithLabelValues(controller, "Deployment")), 1},
+		{"ConfigMap apply error", testutil.ToFloat64(applyErrors.WithLabelValues(controller, "ConfigMap")), 0},
+	} {
+		if test.counter != test.expected {
+			t.Errorf("testcase %s: expected %v, got %v", test.name, test.expected, test.counter)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -15,8 +16,9 @@ var (
 	tlsKey = "/etc/secrets/tls.key"
 )
 
-// BuildServer creates the http.Server struct
-func BuildServer(port int) *http.Server {
+// BuildServer creates the http.Server struct. When authFilter is set, /metrics is only served to the requests it
+// lets through.
+func BuildServer(port int, authFilter *AuthFilter) *http.Server {
 	if port <= 0 {
 		klog.Error("invalid port for metric server")
 		return nil
@@ -24,7 +26,11 @@ func BuildServer(port int) *http.Server {
 
 	bindAddr := fmt.Sprintf(":%d", port)
 	router := http.NewServeMux()
-	router.Handle("/metrics", promhttp.Handler())
+	var metricsHandler http.Handler = promhttp.Handler()
+	if authFilter != nil {
+		metricsHandler = authFilter.WithAuth(metricsHandler)
+	}
+	router.Handle("/metrics", metricsHandler)
 	srv := &http.Server{
 		Addr:    bindAddr,
 		Handler: router,
@@ -33,7 +39,7 @@ func BuildServer(port int) *http.Server {
 	return srv
 }
 
-// StopServer stops the server; for tls secret rotation
+// StopServer gracefully stops the server
 func StopServer(srv *http.Server) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -42,16 +48,22 @@ func StopServer(srv *http.Server) {
 	}
 }
 
-// RunServer starts the metrics server.
+// RunServer starts the metrics server. Outside of a local run, it serves the certificate in tlsCRT and tlsKey,
+// reloading it when the secret is rotated.
 func RunServer(srv *http.Server, stopCh <-chan struct{}, kubeconfig string) {
 	go func() {
-		var err error
 		if len(kubeconfig) == 0 {
-			err = srv.ListenAndServeTLS(tlsCRT, tlsKey)
-		} else {
-			err = srv.ListenAndServeTLS("", "")
+			reloader, err := newCertReloader(tlsCRT, tlsKey)
+			if err != nil {
+				klog.Errorf("error starting metrics server: %v", err)
+				return
+			}
+			if err := reloader.watch(stopCh); err != nil {
+				klog.Errorf("error watching metrics serving certificate, it will not be reloaded: %v", err)
+			}
+			srv.TLSConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
 		}
-		if err != nil && err != http.ErrServerClosed {
+		if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			klog.Errorf("error starting metrics server: %v", err)
 		}
 	}()
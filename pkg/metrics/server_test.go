@@ -22,6 +22,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -112,7 +113,7 @@ func runMetricsServer(t *testing.T) (int, chan<- struct{}) {
 	port := MetricsPort + int(atomic.AddUint32(&portOffset, 1))
 
 	ch := make(chan struct{})
-	server := BuildServer(port)
+	server := BuildServer(port, nil)
 	go RunServer(server, ch, "")
 
 	if err := blockUntilServerStarted(port); err != nil {
@@ -186,6 +187,7 @@ func TestMetricQueries(t *testing.T) {
 			secretLister: &fakeSecretShareLister{
 				secretShares: []*v1alpha1.SharedSecret{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name"},
 						Spec: v1alpha1.SharedSecretSpec{
 							SecretRef: v1alpha1.SharedSecretReference{
 								Name:      "secret-name",
@@ -198,6 +200,7 @@ func TestMetricQueries(t *testing.T) {
 			cmLister: &fakeConfigMapShareLister{
 				cmShares: []*v1alpha1.SharedConfigMap{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name"},
 						Spec: v1alpha1.SharedConfigMapSpec{
 							ConfigMapRef: v1alpha1.SharedConfigMapReference{
 								Name:      "config-map-name",
@@ -217,6 +220,7 @@ func TestMetricQueries(t *testing.T) {
 			secretLister: &fakeSecretShareLister{
 				secretShares: []*v1alpha1.SharedSecret{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name"},
 						Spec: v1alpha1.SharedSecretSpec{
 							SecretRef: v1alpha1.SharedSecretReference{
 								Name:      "secret-name",
@@ -225,6 +229,7 @@ func TestMetricQueries(t *testing.T) {
 						},
 					},
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret-name-2"},
 						Spec: v1alpha1.SharedSecretSpec{
 							SecretRef: v1alpha1.SharedSecretReference{
 								Name:      "secret-name-2",
@@ -250,6 +255,7 @@ func TestMetricQueries(t *testing.T) {
 			cmLister: &fakeConfigMapShareLister{
 				cmShares: []*v1alpha1.SharedConfigMap{
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name"},
 						Spec: v1alpha1.SharedConfigMapSpec{
 							ConfigMapRef: v1alpha1.SharedConfigMapReference{
 								Name:      "config-map-name",
@@ -258,6 +264,7 @@ func TestMetricQueries(t *testing.T) {
 						},
 					},
 					{
+						ObjectMeta: metav1.ObjectMeta{Name: "config-map-name-2"},
 						Spec: v1alpha1.SharedConfigMapSpec{
 							ConfigMapRef: v1alpha1.SharedConfigMapReference{
 								Name:      "config-map-name-2",
@@ -269,11 +276,7 @@ func TestMetricQueries(t *testing.T) {
 			},
 		},
 	} {
-		sc = sharesCollector{
-			sharedSecretLister:    test.secretLister,
-			sharedConfigMapLister: test.cmLister,
-			isCreated:             true,
-		}
+		resetShareCollector(test.secretLister, test.cmLister)
 		prometheus.MustRegister(&sc)
 
 		port, ch := runMetricsServer(t)
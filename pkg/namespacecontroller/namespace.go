@@ -0,0 +1,221 @@
+package namespacecontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
+)
+
+const (
+	controllerName      = "SharedResourceNamespaceLabelController"
+	skipValidationLabel = "csi.sharedresource.openshift.io/skip-validation"
+
+	// NamespaceLabelsDegradedConditionType is reported on the ClusterCSIDriver when the labels cannot be applied.
+	NamespaceLabelsDegradedConditionType = "NamespaceLabelsDegraded"
+
+	resyncInterval = 10 * time.Minute
+)
+
+var (
+	// the driver DaemonSet runs privileged pods in the operator namespace
+	operatorNamespaceLabels = map[string]string{
+		skipValidationLabel:                  "true",
+		"pod-security.kubernetes.io/enforce": "privileged",
+		"pod-security.kubernetes.io/audit":   "privileged",
+		"pod-security.kubernetes.io/warn":    "privileged",
+	}
+	skipValidationLabels = map[string]string{
+		skipValidationLabel: "true",
+	}
+)
+
+// namespaceLabelController keeps the skip-validation label, and for the operator namespace the Pod Security
+// labels the driver needs, on the operator namespace and on every namespace listed in the
+// skipValidationNamespaces operator configuration. Labels are added with merge patches, so labels owned by
+// others are never touched. Namespaces dropped from the configuration keep the label until removed by hand.
+// The operator namespace is labelled even when the operator configuration is invalid.
+type namespaceLabelController struct {
+	kubeClient      kubernetes.Interface
+	operatorClient  v1helpers.OperatorClient
+	namespaceLister corev1listers.NamespaceLister
+	configMapLister corev1listers.ConfigMapNamespaceLister
+
+	// managedLock guards managed, the namespaces labelled by the last sync, which filters the namespace events
+	// without parsing the operator configuration on each of them
+	managedLock sync.Mutex
+	managed     map[string]bool
+}
+
+func NewNamespaceLabelController(kubeClient kubernetes.Interface,
+	operatorClient v1helpers.OperatorClient,
+	namespaceInformer corev1informers.NamespaceInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	recorder events.Recorder) factory.Controller {
+
+	c := &namespaceLabelController{
+		kubeClient:      kubeClient,
+		operatorClient:  operatorClient,
+		namespaceLister: namespaceInformer.Lister(),
+		configMapLister: configMapInformer.Lister().ConfigMaps(config.DefaultNamespace),
+	}
+	return factory.New().WithFilteredEventsInformers(
+		c.isManagedNamespace,
+		namespaceInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(config.OperatorConfigMapName),
+		configMapInformer.Informer(),
+	).WithSync(
+		metrics.InstrumentSync(controllerName, c.sync),
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		metrics.InstrumentRecorder(controllerName, recorder.WithComponentSuffix("shared-resource-namespace-label-controller")),
+	)
+}
+
+func (c *namespaceLabelController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	desired, configErr := c.desiredLabels()
+
+	names := make([]string, 0, len(desired))
+	managed := map[string]bool{}
+	for name := range desired {
+		names = append(names, name)
+		managed[name] = true
+	}
+	sort.Strings(names)
+	c.managedLock.Lock()
+	c.managed = managed
+	c.managedLock.Unlock()
+
+	var errs []error
+	for _, name := range names {
+		if err := c.ensureLabels(ctx, syncContext.Recorder(), name, desired[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	syncErr := v1helpers.NewMultiLineAggregate(errs)
+	reason := ""
+	conditionErr := syncErr
+	switch {
+	case configErr != nil:
+		// nothing will change until the operator configuration is fixed, so it is reported rather than retried
+		reason = "InvalidConfiguration"
+		conditionErr = v1helpers.NewMultiLineAggregate(append([]error{configErr}, errs...))
+	case syncErr != nil:
+		reason = "PatchFailed"
+	}
+	if err := c.updateCondition(ctx, reason, conditionErr); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// desiredLabels returns the labels to maintain, keyed by namespace name. When the operator configuration is
+// invalid, only the labels of the operator namespace are returned, along with the configuration error.
+func (c *namespaceLabelController) desiredLabels() (map[string]map[string]string, error) {
+	desired := map[string]map[string]string{
+		config.DefaultNamespace: operatorNamespaceLabels,
+	}
+	opConfig, err := config.GetOperatorConfig(c.configMapLister)
+	if err != nil {
+		return desired, err
+	}
+	for _, ns := range opConfig.SkipValidationNamespaces {
+		if ns != config.DefaultNamespace {
+			desired[ns] = skipValidationLabels
+		}
+	}
+	return desired, nil
+}
+
+func (c *namespaceLabelController) ensureLabels(ctx context.Context, recorder events.Recorder, name string, labels map[string]string) error {
+	ns, err := c.namespaceLister.Get(name)
+	if kerrors.IsNotFound(err) {
+		// labelled as soon as the informer sees it created
+		klog.V(4).Infof("Namespace %q does not exist, not labelling it", name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unexpected error determining if %q exists: %s", name, err)
+	}
+
+	missing := missingLabels(ns, labels)
+	if len(missing) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": missing,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.kubeClient.CoreV1().Namespaces().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("unable to patch namespace %q with labels %v: %s", name, missing, err)
+	}
+	recorder.Eventf("NamespaceLabelsUpdated", "Set labels %v on namespace %s", missing, name)
+	metrics.RecordObjectRecreated(controllerName, "NamespaceLabels")
+	return nil
+}
+
+func (c *namespaceLabelController) isManagedNamespace(obj interface{}) bool {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		// tombstones and other unexpected objects are cheap enough to sync on
+		return true
+	}
+	if ns.Name == config.DefaultNamespace {
+		return true
+	}
+	// the namespaces added to the configuration are synced on the configuration change that adds them
+	c.managedLock.Lock()
+	defer c.managedLock.Unlock()
+	return c.managed[ns.Name]
+}
+
+func (c *namespaceLabelController) updateCondition(ctx context.Context, reason string, err error) error {
+	condition := operatorv1.OperatorCondition{
+		Type:   NamespaceLabelsDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if err != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = err.Error()
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return updateErr
+}
+
+// missingLabels returns the entries of labels that ns lacks or holds with a different value.
+func missingLabels(ns *corev1.Namespace, labels map[string]string) map[string]string {
+	missing := map[string]string{}
+	for k, v := range labels {
+		if current, ok := ns.Labels[k]; !ok || current != v {
+			missing[k] = v
+		}
+	}
+	return missing
+}
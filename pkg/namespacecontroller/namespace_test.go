@@ -0,0 +1,139 @@
+package namespacecontroller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		namespaces     []*corev1.Namespace
+		operatorConfig string
+		expectPatched  []string
+		expectDegraded bool
+		expectManaged  []string
+	}{
+		{
+			name:          "namespace without labels is patched",
+			namespaces:    []*corev1.Namespace{namespace(config.DefaultNamespace, nil)},
+			expectPatched: []string{config.DefaultNamespace},
+		},
+		{
+			name:       "labelled namespace is left alone",
+			namespaces: []*corev1.Namespace{namespace(config.DefaultNamespace, operatorNamespaceLabels)},
+		},
+		{
+			name: "configured namespaces are labelled, missing ones skipped",
+			namespaces: []*corev1.Namespace{
+				namespace(config.DefaultNamespace, operatorNamespaceLabels),
+				namespace("build-infra", map[string]string{"team": "builds"}),
+			},
+			operatorConfig: "skipValidationNamespaces: [build-infra, not-created-yet]\n",
+			expectPatched:  []string{"build-infra"},
+			expectManaged:  []string{"build-infra", "not-created-yet"},
+		},
+		{
+			name: "operator namespace is labelled despite an invalid configuration",
+			namespaces: []*corev1.Namespace{
+				namespace(config.DefaultNamespace, nil),
+				namespace("build-infra", nil),
+			},
+			operatorConfig: "skipValidationNamespace: [build-infra]\n",
+			expectPatched:  []string{config.DefaultNamespace},
+			expectDegraded: true,
+		},
+	} {
+		nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		objects := []runtime.Object{}
+		for _, ns := range test.namespaces {
+			nsIndexer.Add(ns)
+			objects = append(objects, ns)
+		}
+		cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if len(test.operatorConfig) > 0 {
+			cmIndexer.Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: config.OperatorConfigMapName, Namespace: config.DefaultNamespace},
+				Data:       map[string]string{config.ConfigKey: test.operatorConfig},
+			})
+		}
+
+		client := fake.NewSimpleClientset(objects...)
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &namespaceLabelController{
+			kubeClient:      client,
+			operatorClient:  operatorClient,
+			namespaceLister: corev1listers.NewNamespaceLister(nsIndexer),
+			configMapLister: corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+		}
+
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, events.NewInMemoryRecorder(controllerName))); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		patched := []string{}
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "update" {
+				t.Errorf("testcase %s: namespaces must be patched, not updated", test.name)
+			}
+			if patch, ok := action.(clienttesting.PatchAction); ok {
+				patched = append(patched, patch.GetName())
+			}
+		}
+		if len(patched) != len(test.expectPatched) {
+			t.Fatalf("testcase %s: expected patched namespaces %v, got %v", test.name, test.expectPatched, patched)
+		}
+		for i := range patched {
+			if patched[i] != test.expectPatched[i] {
+				t.Errorf("testcase %s: expected patched namespaces %v, got %v", test.name, test.expectPatched, patched)
+			}
+		}
+		for _, name := range test.expectPatched {
+			ns, err := client.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+			}
+			if ns.Labels[skipValidationLabel] != "true" {
+				t.Errorf("testcase %s: namespace %s lacks label %s: %v", test.name, name, skipValidationLabel, ns.Labels)
+			}
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		if test.expectDegraded {
+			condition := v1helpers.FindOperatorCondition(status.Conditions, NamespaceLabelsDegradedConditionType)
+			if condition == nil || condition.Status != operatorv1.ConditionTrue || condition.Reason != "InvalidConfiguration" {
+				t.Errorf("testcase %s: expected condition %s to be True with reason InvalidConfiguration, got %v", test.name, NamespaceLabelsDegradedConditionType, status.Conditions)
+			}
+		} else if !v1helpers.IsOperatorConditionFalse(status.Conditions, NamespaceLabelsDegradedConditionType) {
+			t.Errorf("testcase %s: expected condition %s to be False, got %v", test.name, NamespaceLabelsDegradedConditionType, status.Conditions)
+		}
+
+		for _, name := range append([]string{config.DefaultNamespace}, test.expectManaged...) {
+			if !c.isManagedNamespace(namespace(name, nil)) {
+				t.Errorf("testcase %s: expected events of namespace %s to be synced", test.name, name)
+			}
+		}
+		if c.isManagedNamespace(namespace("unrelated", nil)) {
+			t.Errorf("testcase %s: expected events of namespace unrelated to be filtered out", test.name)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package nodecontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	storagev1informers "k8s.io/client-go/informers/storage/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	storagev1listers "k8s.io/client-go/listers/storage/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
+)
+
+const (
+	controllerName = "SharedResourceNodeServiceController"
+	driverName     = "csi.sharedresource.openshift.io"
+
+	// NodeServiceDegradedConditionType is reported on the ClusterCSIDriver while nodes running a driver pod do not
+	// have a registered, ready driver.
+	NodeServiceDegradedConditionType = "NodeServiceDegraded"
+
+	// registrationGracePeriod is how long a driver pod may take to register and become ready before its node is
+	// reported
+	registrationGracePeriod = 2 * time.Minute
+	// maxReportedNodes is how many node names the condition message lists
+	maxReportedNodes = 5
+
+	resyncInterval = 10 * time.Minute
+)
+
+var (
+	driverPodSelector = labels.SelectorFromSet(labels.Set{"app": "shared-resource-csi-driver-node"})
+
+	now = time.Now
+)
+
+// nodeServiceController reports the nodes whose driver pod has not registered the driver with the kubelet, or is
+// not ready, e.g. because it is crashlooping. Only nodes running a driver pod are considered, so nodes left out
+// by the node placement are not reported.
+type nodeServiceController struct {
+	operatorClient v1helpers.OperatorClient
+	podLister      corev1listers.PodNamespaceLister
+	csiNodeLister  storagev1listers.CSINodeLister
+}
+
+func NewNodeServiceController(operatorClient v1helpers.OperatorClient,
+	podInformer corev1informers.PodInformer,
+	csiNodeInformer storagev1informers.CSINodeInformer,
+	recorder events.Recorder) factory.Controller {
+
+	c := &nodeServiceController{
+		operatorClient: operatorClient,
+		podLister:      podInformer.Lister().Pods(config.DefaultNamespace),
+		csiNodeLister:  csiNodeInformer.Lister(),
+	}
+	return factory.New().WithFilteredEventsInformers(
+		isDriverPod,
+		podInformer.Informer(),
+	).WithInformers(
+		csiNodeInformer.Informer(),
+	).WithSync(
+		c.sync,
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		recorder.WithComponentSuffix("shared-resource-node-service-controller"),
+	)
+}
+
+func (c *nodeServiceController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	pods, err := c.podLister.List(driverPodSelector)
+	if err != nil {
+		return err
+	}
+
+	states := map[string]metrics.DriverNodeState{}
+	unhealthy := []string{}
+	// the condition reasons of the reported nodes
+	reasons := sets.New[string]()
+	var recheckAfter time.Duration
+	for _, pod := range pods {
+		node := pod.Spec.NodeName
+		if len(node) == 0 || pod.DeletionTimestamp != nil {
+			continue
+		}
+		registered, err := c.isRegistered(node)
+		if err != nil {
+			return err
+		}
+		states[node] = metrics.DriverNodeState{Registered: registered, Restarts: restarts(pod)}
+
+		reason, problem, since := "", "", pod.CreationTimestamp.Time
+		switch ready := readyCondition(pod); {
+		case !registered:
+			reason = "DriverNotRegistered"
+			problem = "driver not registered in the CSINode"
+		case ready == nil || ready.Status != corev1.ConditionTrue:
+			reason = "DriverPodNotReady"
+			problem = fmt.Sprintf("driver pod %s not ready", pod.Name)
+			if ready != nil && ready.LastTransitionTime.After(since) {
+				since = ready.LastTransitionTime.Time
+			}
+		default:
+			continue
+		}
+		if remaining := since.Add(registrationGracePeriod).Sub(now()); remaining > 0 {
+			// reported once the pod has had the time to register
+			if recheckAfter == 0 || remaining < recheckAfter {
+				recheckAfter = remaining
+			}
+			continue
+		}
+		reasons.Insert(reason)
+		unhealthy = append(unhealthy, fmt.Sprintf("%s (%s)", node, problem))
+	}
+	metrics.SetDriverNodes(states)
+	if recheckAfter > 0 {
+		syncContext.Queue().AddAfter(syncContext.QueueKey(), recheckAfter)
+	}
+
+	condition := operatorv1.OperatorCondition{
+		Type:   NodeServiceDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if len(unhealthy) > 0 {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = "NodesUnhealthy"
+		if reasons.Len() == 1 {
+			condition.Reason = sets.List(reasons)[0]
+		}
+		condition.Message = unhealthyMessage(unhealthy)
+	}
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return err
+}
+
+// isRegistered is true when the CSINode of node lists the driver.
+func (c *nodeServiceController) isRegistered(node string) (bool, error) {
+	csiNode, err := c.csiNodeLister.Get(node)
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unexpected error determining if CSINode %q exists: %s", node, err)
+	}
+	for _, driver := range csiNode.Spec.Drivers {
+		if driver.Name == driverName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// unhealthyMessage lists the first maxReportedNodes entries of unhealthy, in name order.
+func unhealthyMessage(unhealthy []string) string {
+	sort.Strings(unhealthy)
+	message := fmt.Sprintf("The %s driver is not available on %d node(s): ", driverName, len(unhealthy))
+	if len(unhealthy) <= maxReportedNodes {
+		return message + strings.Join(unhealthy, ", ")
+	}
+	return message + fmt.Sprintf("%s and %d more", strings.Join(unhealthy[:maxReportedNodes], ", "), len(unhealthy)-maxReportedNodes)
+}
+
+func readyCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodReady {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func restarts(pod *corev1.Pod) int32 {
+	var count int32
+	for _, status := range pod.Status.ContainerStatuses {
+		count += status.RestartCount
+	}
+	return count
+}
+
+func isDriverPod(obj interface{}) bool {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		// tombstones and other unexpected objects are cheap enough to sync on
+		return true
+	}
+	return pod.Namespace == config.DefaultNamespace && driverPodSelector.Matches(labels.Set(pod.Labels))
+}
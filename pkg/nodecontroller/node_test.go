@@ -0,0 +1,160 @@
+package nodecontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	storagev1listers "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func driverPod(node string, age time.Duration, ready bool, restarts int32) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	created := metav1.NewTime(now().Add(-age))
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "shared-resource-csi-driver-node-" + node,
+			Namespace:         config.DefaultNamespace,
+			Labels:            map[string]string{"app": "shared-resource-csi-driver-node"},
+			CreationTimestamp: created,
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: status, LastTransitionTime: created}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "hostpath", RestartCount: restarts}},
+		},
+	}
+}
+
+func csiNode(node string, drivers ...string) *storagev1.CSINode {
+	csiNode := &storagev1.CSINode{ObjectMeta: metav1.ObjectMeta{Name: node}}
+	for _, driver := range drivers {
+		csiNode.Spec.Drivers = append(csiNode.Spec.Drivers, storagev1.CSINodeDriver{Name: driver, NodeID: node})
+	}
+	return csiNode
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		pods           []*corev1.Pod
+		csiNodes       []*storagev1.CSINode
+		expectDegraded bool
+		expectReason   string
+		expectMessage  string
+		expectMetrics  string
+	}{
+		{
+			name:     "all nodes registered",
+			pods:     []*corev1.Pod{driverPod("node-a", time.Hour, true, 0), driverPod("node-b", time.Hour, true, 2)},
+			csiNodes: []*storagev1.CSINode{csiNode("node-a", driverName), csiNode("node-b", "other.csi.example.com", driverName)},
+			expectMetrics: `
+openshift_csi_share_driver_node_registered{node="node-a"} 1
+openshift_csi_share_driver_node_registered{node="node-b"} 1
+`,
+		},
+		{
+			name:     "pod still starting",
+			pods:     []*corev1.Pod{driverPod("node-a", time.Minute, false, 0)},
+			csiNodes: []*storagev1.CSINode{csiNode("node-a")},
+			expectMetrics: `
+openshift_csi_share_driver_node_registered{node="node-a"} 0
+`,
+		},
+		{
+			name:           "unregistered and crashlooping nodes",
+			pods:           []*corev1.Pod{driverPod("node-a", time.Hour, true, 0), driverPod("node-b", time.Hour, false, 7), driverPod("node-c", time.Hour, true, 0)},
+			csiNodes:       []*storagev1.CSINode{csiNode("node-a"), csiNode("node-b", driverName)},
+			expectDegraded: true,
+			expectReason:   "NodesUnhealthy",
+			expectMessage: "not available on 3 node(s): node-a (driver not registered in the CSINode), " +
+				"node-b (driver pod shared-resource-csi-driver-node-node-b not ready), node-c (driver not registered in the CSINode)",
+			expectMetrics: `
+openshift_csi_share_driver_node_registered{node="node-a"} 0
+openshift_csi_share_driver_node_registered{node="node-b"} 1
+openshift_csi_share_driver_node_registered{node="node-c"} 0
+`,
+		},
+		{
+			name: "message is truncated",
+			pods: func() []*corev1.Pod {
+				pods := []*corev1.Pod{}
+				for i := 0; i < maxReportedNodes+3; i++ {
+					pods = append(pods, driverPod(fmt.Sprintf("node-%d", i), time.Hour, true, 0))
+				}
+				return pods
+			}(),
+			expectDegraded: true,
+			expectReason:   "DriverNotRegistered",
+			expectMessage: "not available on 8 node(s): node-0 (driver not registered in the CSINode), " +
+				"node-1 (driver not registered in the CSINode), node-2 (driver not registered in the CSINode), " +
+				"node-3 (driver not registered in the CSINode), node-4 (driver not registered in the CSINode) and 3 more",
+		},
+		{
+			name:           "crashlooping node",
+			pods:           []*corev1.Pod{driverPod("node-a", time.Hour, false, 7)},
+			csiNodes:       []*storagev1.CSINode{csiNode("node-a", driverName)},
+			expectDegraded: true,
+			expectReason:   "DriverPodNotReady",
+			expectMessage:  "not available on 1 node(s): node-a (driver pod shared-resource-csi-driver-node-node-a not ready)",
+		},
+	} {
+		podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, pod := range test.pods {
+			podIndexer.Add(pod)
+		}
+		csiNodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, csiNode := range test.csiNodes {
+			csiNodeIndexer.Add(csiNode)
+		}
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &nodeServiceController{
+			operatorClient: operatorClient,
+			podLister:      corev1listers.NewPodLister(podIndexer).Pods(config.DefaultNamespace),
+			csiNodeLister:  storagev1listers.NewCSINodeLister(csiNodeIndexer),
+		}
+
+		recorder := events.NewInMemoryRecorder(controllerName)
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, recorder)); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		condition := v1helpers.FindOperatorCondition(status.Conditions, NodeServiceDegradedConditionType)
+		if condition == nil {
+			t.Fatalf("testcase %s: expected condition %s, got %v", test.name, NodeServiceDegradedConditionType, status.Conditions)
+		}
+		if degraded := condition.Status == operatorv1.ConditionTrue; degraded != test.expectDegraded || condition.Reason != test.expectReason {
+			t.Errorf("testcase %s: expected condition %s to be %v with reason %q, got %v", test.name, NodeServiceDegradedConditionType, test.expectDegraded, test.expectReason, condition)
+		}
+		if !strings.Contains(condition.Message, test.expectMessage) {
+			t.Errorf("testcase %s: expected message containing %q, got %q", test.name, test.expectMessage, condition.Message)
+		}
+		if len(test.expectMetrics) > 0 {
+			expected := "# HELP openshift_csi_share_driver_node_registered Whether the CSI shared resource driver is registered in the CSINode of a node running a driver pod\n" +
+				"# TYPE openshift_csi_share_driver_node_registered gauge" + test.expectMetrics
+			if err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "openshift_csi_share_driver_node_registered"); err != nil {
+				t.Errorf("testcase %s: %v", test.name, err)
+			}
+		}
+	}
+}
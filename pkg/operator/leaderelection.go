@@ -0,0 +1,188 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	configv1 "github.com/openshift/api/config/v1"
+	leaderelectionconverter "github.com/openshift/library-go/pkg/config/leaderelection"
+	"github.com/openshift/library-go/pkg/controller/controllercmd"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
+)
+
+const leaseName = operatorName + "-lock"
+
+// LeaderElectionOptions holds the timings of the Lease based leader election that lets several operator
+// replicas run, with only the current leader running the controllers.
+type LeaderElectionOptions struct {
+	Disable       bool
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// NewLeaderElectionOptions returns the options defaulted to the timings recommended for OpenShift components.
+func NewLeaderElectionOptions() *LeaderElectionOptions {
+	defaults := leaderelectionconverter.LeaderElectionDefaulting(configv1.LeaderElection{}, "", "")
+	return &LeaderElectionOptions{
+		LeaseDuration: defaults.LeaseDuration.Duration,
+		RenewDeadline: defaults.RenewDeadline.Duration,
+		RetryPeriod:   defaults.RetryPeriod.Duration,
+	}
+}
+
+func (o *LeaderElectionOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Disable, "disable-leader-election", o.Disable, "Run the controllers without acquiring the leader Lease. Only safe with a single replica.")
+	flags.DurationVar(&o.LeaseDuration, "leader-election-lease-duration", o.LeaseDuration, "How long non-leaders wait after the last renewal before taking over the leader Lease.")
+	flags.DurationVar(&o.RenewDeadline, "leader-election-renew-deadline", o.RenewDeadline, "How long the leader keeps retrying to renew the Lease before giving up leadership.")
+	flags.DurationVar(&o.RetryPeriod, "leader-election-retry-period", o.RetryPeriod, "How long to wait between attempts to acquire or renew the Lease.")
+}
+
+// Validate checks the timings the same way the client-go leader elector does, so that a bad combination
+// is reported instead of panicking.
+func (o *LeaderElectionOptions) Validate() error {
+	if o.Disable {
+		return nil
+	}
+	if o.RetryPeriod <= 0 {
+		return fmt.Errorf("--leader-election-retry-period must be greater than zero")
+	}
+	if o.LeaseDuration <= o.RenewDeadline {
+		return fmt.Errorf("--leader-election-lease-duration must be greater than --leader-election-renew-deadline")
+	}
+	if o.RenewDeadline <= time.Duration(leaderelection.JitterFactor*float64(o.RetryPeriod)) {
+		return fmt.Errorf("--leader-election-renew-deadline must be greater than %v times --leader-election-retry-period", leaderelection.JitterFactor)
+	}
+	return nil
+}
+
+// RunWithLeaderElection serves the metrics on every replica and runs RunOperator only while holding the leader
+// Lease. When leadership is lost the controllers are stopped and the function returns, so the process restarts
+// as a candidate. On shutdown the Lease is released once the controllers have stopped, so another replica takes
+// over after at most one retry period.
+func RunWithLeaderElection(ctx context.Context, controllerConfig *controllercmd.ControllerContext, kubeconfig, guestKubeconfig string, opts *LeaderElectionOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	klog.Info("Starting metrics endpoint")
+	metricsClient := kubeclient.NewForConfigOrDie(rest.AddUserAgent(controllerConfig.KubeConfig, operatorName+"-metrics"))
+	authFilter := metrics.NewAuthFilter(metricsClient.AuthenticationV1(), metricsClient.AuthorizationV1())
+	server := metrics.BuildServer(metrics.MetricsPort, authFilter)
+	go metrics.RunServer(server, ctx.Done(), kubeconfig)
+
+	if opts.Disable {
+		klog.Warning("Leader election is disabled")
+		return RunOperator(ctx, controllerConfig, kubeconfig, guestKubeconfig)
+	}
+
+	namespace := controllerConfig.OperatorNamespace
+	if len(namespace) == 0 {
+		namespace = config.DefaultNamespace
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	identity := fmt.Sprintf("%s_%s", hostname, uuid.NewUUID())
+
+	kubeClient := kubeclient.NewForConfigOrDie(rest.AddUserAgent(controllerConfig.KubeConfig, operatorName+"-leader-election"))
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		namespace,
+		leaseName,
+		kubeClient.CoreV1(),
+		kubeClient.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity},
+	)
+	if err != nil {
+		return err
+	}
+
+	// The election runs on its own context, which is only cancelled once the controllers have stopped, so
+	// that the Lease is not released to another replica while this one may still be writing.
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+
+	leading := make(chan struct{})
+	operatorDone := make(chan struct{})
+	var runErr error
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-electionCtx.Done():
+			return
+		}
+		select {
+		case <-leading:
+			<-operatorDone
+		default:
+		}
+		cancelElection()
+	}()
+
+	klog.Infof("Attempting to acquire leader Lease %s/%s as %s", namespace, leaseName, identity)
+	leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            leaseName,
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				close(leading)
+				// give up the Lease if the controllers return on their own
+				defer cancelElection()
+				defer close(operatorDone)
+
+				// stop the controllers both on leadership loss and on shutdown
+				runCtx, cancel := context.WithCancel(leaderCtx)
+				defer cancel()
+				go func() {
+					select {
+					case <-ctx.Done():
+						cancel()
+					case <-runCtx.Done():
+					}
+				}()
+
+				klog.Infof("Became leader as %s, starting the controllers", identity)
+				controllerConfig.EventRecorder.Eventf("LeaderElection", "%s became leader", identity)
+				runErr = RunOperator(runCtx, controllerConfig, kubeconfig, guestKubeconfig)
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("%s stopped leading", identity)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					klog.Infof("The current leader is %s", current)
+				}
+			},
+		},
+	})
+
+	select {
+	case <-leading:
+		<-operatorDone
+	default:
+	}
+	if runErr == nil && ctx.Err() == nil {
+		klog.Warningf("Lost the leader Lease %s/%s, exiting", namespace, leaseName)
+	}
+	return runErr
+}
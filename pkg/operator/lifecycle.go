@@ -0,0 +1,142 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const defaultShutdownGracePeriod = 10 * time.Second
+
+// informerFactory is implemented by the client-go, dynamic and generated shared informer factories, whose
+// WaitForCacheSync only differ in the key type of the returned map.
+type informerFactory[K comparable] interface {
+	Start(stopCh <-chan struct{})
+	WaitForCacheSync(stopCh <-chan struct{}) map[K]bool
+}
+
+type lifecycleStep struct {
+	name string
+	// informers steps start the informers and block until their caches have synced
+	startInformers   func(stopCh <-chan struct{})
+	waitForCacheSync func(stopCh <-chan struct{}) bool
+	// controller steps run in their own goroutine until the context is cancelled
+	runController func(ctx context.Context)
+	// func steps run inline and stop the startup when they fail
+	runFunc func(ctx context.Context) error
+}
+
+// lifecycle starts the operator components in the order they were added and stops them all, within a
+// grace period, once the context passed to Run is cancelled.
+type lifecycle struct {
+	gracePeriod time.Duration
+	steps       []lifecycleStep
+}
+
+func newLifecycle(gracePeriod time.Duration) *lifecycle {
+	return &lifecycle{gracePeriod: gracePeriod}
+}
+
+// addInformers adds a step starting the informers requested so far from factory. Later steps only start
+// once their caches have synced.
+func addInformers[K comparable](l *lifecycle, name string, factory informerFactory[K]) {
+	l.steps = append(l.steps, lifecycleStep{
+		name:           name,
+		startInformers: factory.Start,
+		waitForCacheSync: func(stopCh <-chan struct{}) bool {
+			for _, synced := range factory.WaitForCacheSync(stopCh) {
+				if !synced {
+					return false
+				}
+			}
+			return true
+		},
+	})
+}
+
+// addController adds a step running run in its own goroutine. run must return once its context is done.
+func (l *lifecycle) addController(name string, run func(ctx context.Context)) {
+	l.steps = append(l.steps, lifecycleStep{name: name, runController: run})
+}
+
+// addFunc adds a step running f inline. An error from f stops everything started before it.
+func (l *lifecycle) addFunc(name string, f func(ctx context.Context) error) {
+	l.steps = append(l.steps, lifecycleStep{name: name, runFunc: f})
+}
+
+// Run starts every step and blocks until ctx is cancelled or a step fails. It returns nil when all the
+// components stopped within the grace period after ctx was cancelled.
+func (l *lifecycle) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	running := map[string]bool{}
+
+	var startErr error
+	for _, step := range l.steps {
+		if runCtx.Err() != nil {
+			break
+		}
+		switch {
+		case step.startInformers != nil:
+			klog.Infof("Starting %s and waiting for their caches to sync", step.name)
+			step.startInformers(runCtx.Done())
+			if !step.waitForCacheSync(runCtx.Done()) {
+				klog.Infof("Stopped before the caches of %s synced", step.name)
+			}
+		case step.runController != nil:
+			klog.Infof("Starting %s", step.name)
+			lock.Lock()
+			running[step.name] = true
+			lock.Unlock()
+			wg.Add(1)
+			go func(step lifecycleStep) {
+				defer wg.Done()
+				step.runController(runCtx)
+				lock.Lock()
+				delete(running, step.name)
+				lock.Unlock()
+			}(step)
+		case step.runFunc != nil:
+			klog.Infof("Running %s", step.name)
+			if err := step.runFunc(runCtx); err != nil {
+				startErr = fmt.Errorf("error running %s: %w", step.name, err)
+			}
+		}
+		if startErr != nil {
+			break
+		}
+	}
+
+	if startErr == nil {
+		<-runCtx.Done()
+	}
+	klog.Info("Stopping the operator components")
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		klog.Info("All operator components stopped")
+		return startErr
+	case <-time.After(l.gracePeriod):
+		lock.Lock()
+		names := make([]string, 0, len(running))
+		for name := range running {
+			names = append(names, name)
+		}
+		lock.Unlock()
+		sort.Strings(names)
+		return fmt.Errorf("timed out after %v waiting for %v to stop", l.gracePeriod, names)
+	}
+}
@@ -0,0 +1,113 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeInformerFactory struct {
+	name   string
+	events *eventLog
+}
+
+func (f *fakeInformerFactory) Start(stopCh <-chan struct{}) {
+	f.events.add("start " + f.name)
+}
+
+func (f *fakeInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[string]bool {
+	f.events.add("synced " + f.name)
+	return map[string]bool{f.name: true}
+}
+
+type eventLog struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (e *eventLog) add(event string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *eventLog) String() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return strings.Join(e.events, ",")
+}
+
+func TestLifecycle(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		failFunc  bool
+		stuck     bool
+		expected  string
+		expectErr string
+	}{
+		{
+			name:     "components start in order and stop cleanly",
+			expected: "start kube,synced kube,start shares,synced shares,func,stop controller",
+		},
+		{
+			name:      "a failing step stops the components already started",
+			failFunc:  true,
+			expected:  "start kube,synced kube,start shares,synced shares,func,stop controller",
+			expectErr: "error running func: failed",
+		},
+		{
+			name:      "components not stopping within the grace period are reported",
+			stuck:     true,
+			expected:  "start kube,synced kube,start shares,synced shares,func",
+			expectErr: "waiting for [controller] to stop",
+		},
+	} {
+		events := &eventLog{}
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		funcDone := make(chan struct{})
+		controllerStarted := make(chan struct{})
+
+		l := newLifecycle(100 * time.Millisecond)
+		addInformers[string](l, "kube", &fakeInformerFactory{name: "kube", events: events})
+		l.addController("controller", func(ctx context.Context) {
+			close(controllerStarted)
+			if test.stuck {
+				<-release
+				return
+			}
+			<-ctx.Done()
+			events.add("stop controller")
+		})
+		addInformers[string](l, "shares", &fakeInformerFactory{name: "shares", events: events})
+		l.addFunc("func", func(ctx context.Context) error {
+			<-controllerStarted
+			events.add("func")
+			close(funcDone)
+			if test.failFunc {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		})
+
+		go func() {
+			<-funcDone
+			cancel()
+		}()
+		err := l.Run(ctx)
+		close(release)
+
+		if len(test.expectErr) == 0 && err != nil {
+			t.Errorf("testcase %s: unexpected error %v", test.name, err)
+		}
+		if len(test.expectErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("testcase %s: expected error containing %q, got %v", test.name, test.expectErr, err)
+		}
+		if events.String() != test.expected {
+			t.Errorf("testcase %s: expected events %s, got %s", test.name, test.expected, events.String())
+		}
+	}
+}
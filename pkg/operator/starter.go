@@ -1,50 +1,66 @@
 package operator
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
-	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 
-	"github.com/ghodss/yaml"
-
 	opv1 "github.com/openshift/api/operator/v1"
 	configclient "github.com/openshift/client-go/config/clientset/versioned"
 	configinformers "github.com/openshift/client-go/config/informers/externalversions"
 	shareclientv1alpha1 "github.com/openshift/client-go/sharedresource/clientset/versioned"
 	shareinformer "github.com/openshift/client-go/sharedresource/informers/externalversions"
+	"github.com/openshift/library-go/pkg/config/client"
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/csi/csicontrollerset"
 	"github.com/openshift/library-go/pkg/operator/csi/csidrivernodeservicecontroller"
 	goc "github.com/openshift/library-go/pkg/operator/genericoperatorclient"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/staticresourcecontroller"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 
 	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/configcontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/crdcontroller"
 	"github.com/openshift/csi-driver-shared-resource-operator/pkg/deploymentcontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/entitlementcontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/grantcontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/hooks"
 	"github.com/openshift/csi-driver-shared-resource-operator/pkg/metrics"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/namespacecontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/nodecontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/statuscontroller"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/webhookcontroller"
 )
 
 const (
 	// Operand and operator run in the same namespace
-	defaultNamespace      = "openshift-cluster-csi-drivers"
 	operatorName          = "csi-driver-shared-resource-operator"
 	operandName           = "csi-driver-shared-resource"
 	metricsCertSecretName = "shared-resource-csi-driver-node-metrics-serving-cert"
-	skipValidationLabel   = "csi.sharedresource.openshift.io/skip-validation"
+	// livenessProbeImageEnvName is the variable library-go replaces ${LIVENESS_PROBE_IMAGE} in node.yaml with
+	livenessProbeImageEnvName = "LIVENESS_PROBE_IMAGE"
 
 	defaultResyncDuration = 20 * time.Minute
 )
@@ -58,76 +74,83 @@ func init() {
 	utilruntime.Must(admissionregistrationv1.AddToScheme(scheme))
 }
 
-func RunOperator(ctx context.Context, controllerConfig *controllercmd.ControllerContext, kubeconfig string) error {
+// RunOperator runs the operator controllers. When guestKubeconfig is set the operator runs in the management
+// cluster of a hosted control plane: the webhook is deployed next to the operator, in the operator namespace, and
+// every other object is managed in the guest cluster that guestKubeconfig points at.
+func RunOperator(ctx context.Context, controllerConfig *controllercmd.ControllerContext, kubeconfig, guestKubeconfig string) error {
+	kubeConfig := controllerConfig.KubeConfig
+	hostedControlPlaneNamespace := ""
+	if len(guestKubeconfig) > 0 {
+		var err error
+		kubeConfig, err = client.GetKubeConfigOrInClusterConfig(guestKubeconfig, nil)
+		if err != nil {
+			return fmt.Errorf("error loading guest kubeconfig %q: %w", guestKubeconfig, err)
+		}
+		hostedControlPlaneNamespace = controllerConfig.OperatorNamespace
+		if len(hostedControlPlaneNamespace) == 0 {
+			return fmt.Errorf("unable to determine the hosted control plane namespace the operator runs in")
+		}
+		klog.Infof("Running in hosted control plane namespace %q, managing the guest cluster of %q", hostedControlPlaneNamespace, guestKubeconfig)
+	}
+	livenessProbeImage := os.Getenv(livenessProbeImageEnvName)
+	if len(livenessProbeImage) == 0 {
+		klog.Warningf("%s is not set, the driver DaemonSet is rolled out without a liveness probe", livenessProbeImageEnvName)
+	}
+
 	// Create core clientset and informers
-	kubeClient := kubeclient.NewForConfigOrDie(rest.AddUserAgent(controllerConfig.KubeConfig, operatorName))
-	kubeInformersForNamespaces := v1helpers.NewKubeInformersForNamespaces(kubeClient, defaultNamespace, "")
-	secretInformer := kubeInformersForNamespaces.InformersFor(defaultNamespace).Core().V1().Secrets()
+	kubeClient := kubeclient.NewForConfigOrDie(rest.AddUserAgent(kubeConfig, operatorName))
+	kubeInformersForNamespaces := v1helpers.NewKubeInformersForNamespaces(kubeClient, config.DefaultNamespace, entitlementcontroller.EntitlementNamespace, "")
+
+	// The webhook runs in the control plane, which is the managed cluster itself unless it is hosted
+	controlPlaneKubeClient := kubeClient
+	controlPlaneInformers := kubeInformersForNamespaces
+	if len(hostedControlPlaneNamespace) > 0 {
+		controlPlaneKubeClient = kubeclient.NewForConfigOrDie(rest.AddUserAgent(controllerConfig.KubeConfig, operatorName))
+		controlPlaneInformers = v1helpers.NewKubeInformersForNamespaces(controlPlaneKubeClient, hostedControlPlaneNamespace)
+	}
+	secretInformer := kubeInformersForNamespaces.InformersFor(config.DefaultNamespace).Core().V1().Secrets()
+	// the pods of every namespace are counted as share consumers by the metrics collector; the informer is
+	// requested here so that it is started with the other kube informers, and only caches what the collector reads
+	consumerPodInformer := kubeInformersForNamespaces.InformersFor("").Core().V1().Pods()
+	if err := consumerPodInformer.Informer().SetTransform(metrics.TransformConsumerPod); err != nil {
+		return err
+	}
+	configMapInformer := kubeInformersForNamespaces.InformersFor(config.DefaultNamespace).Core().V1().ConfigMaps()
+
+	// Only the Roles and RoleBindings generated from share grants are cached
+	grantInformers := informers.NewSharedInformerFactoryWithOptions(kubeClient, defaultResyncDuration,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = grantcontroller.GrantLabel + "=true"
+		}),
+	)
+
+	// The Secrets and ConfigMaps backing shares live in any namespace, only their metadata is cached
+	metadataClient := metadata.NewForConfigOrDie(rest.AddUserAgent(kubeConfig, operatorName))
+	metadataInformers := metadatainformer.NewSharedInformerFactory(metadataClient, defaultResyncDuration)
 
 	// Create config clientset and informer. This is used to get the cluster ID
-	configClient := configclient.NewForConfigOrDie(rest.AddUserAgent(controllerConfig.KubeConfig, operatorName))
+	configClient := configclient.NewForConfigOrDie(rest.AddUserAgent(kubeConfig, operatorName))
 	configInformers := configinformers.NewSharedInformerFactory(configClient, defaultResyncDuration)
 
-	shareClient := shareclientv1alpha1.NewForConfigOrDie(rest.AddUserAgent(controllerConfig.KubeConfig, operatorName))
+	shareClient := shareclientv1alpha1.NewForConfigOrDie(rest.AddUserAgent(kubeConfig, operatorName))
 	shareInformersFactory := shareinformer.NewSharedInformerFactory(shareClient, defaultResyncDuration)
 
-	sharedSecretsLister := shareInformersFactory.Sharedresource().V1alpha1().SharedSecrets().Lister()
-	sharedConfigMapsLister := shareInformersFactory.Sharedresource().V1alpha1().SharedConfigMaps().Lister()
-	if err := metrics.InitializeShareCollector(sharedSecretsLister, sharedConfigMapsLister); err != nil {
-		return err
-	}
-
-	// Create apiextensions clientset for creating the CRDs
-	apiextensionsClient := apiextensionsclient.NewForConfigOrDie(controllerConfig.KubeConfig)
+	// Create apiextensions clientset and informers for managing the CRDs
+	apiextensionsClient := apiextensionsclient.NewForConfigOrDie(kubeConfig)
+	apiextensionsInformers := apiextensionsinformers.NewSharedInformerFactory(apiextensionsClient, defaultResyncDuration)
 
 	// Create GenericOperatorclient. This is used by the library-go controllers created down below
 	gvr := opv1.SchemeGroupVersion.WithResource("clustercsidrivers")
-	operatorClient, dynamicInformers, err := goc.NewClusterScopedOperatorClientWithConfigName(controllerConfig.KubeConfig, gvr, string(opv1.SharedResourcesCSIDriver))
+	operatorClient, dynamicInformers, err := goc.NewClusterScopedOperatorClientWithConfigName(kubeConfig, gvr, string(opv1.SharedResourcesCSIDriver))
 	if err != nil {
 		return err
 	}
 
 	klog.V(5).Info("Generating dynamicClient")
-	dynamicClient, err := dynamic.NewForConfig(controllerConfig.KubeConfig)
-	if err != nil {
-		return err
-	}
-
-	err = ensureCRDSExist(ctx, apiextensionsClient)
-	if err != nil {
-		return err
-	}
-	crdTicker := time.NewTicker(10 * time.Minute)
-	crdDone := make(chan bool)
-	go func() {
-		for {
-			select {
-			case <-crdDone:
-				return
-			case <-crdTicker.C:
-				ensureCRDSExist(ctx, apiextensionsClient)
-			}
-		}
-	}()
-
-	err = ensureConfigurationConfigMapsExists(ctx, kubeClient)
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
 	if err != nil {
 		return err
 	}
-	cmTicker := time.NewTicker(10 * time.Minute)
-	cmDone := make(chan bool)
-	go func() {
-		for {
-			select {
-			case <-cmDone:
-				return
-			case <-cmTicker.C:
-				ensureConfigurationConfigMapsExists(ctx, kubeClient)
-			}
-		}
-	}()
-
-	setSkipValidationLabelForNamespace(ctx, kubeClient)
 
 	csiControllerSet := csicontrollerset.NewCSIControllerSet(
 		operatorClient,
@@ -153,11 +176,6 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 			"rbac/node_binding.yaml",
 			"rbac/prometheus_role.yaml",
 			"rbac/prometheus_rolebinding.yaml",
-			"webhook/sa.yaml",
-			"webhook/configmap.yaml",
-			"webhook/pdb.yaml",
-			"webhook/service.yaml",
-			"webhook/validating_webhook_configuration.yaml",
 		},
 	).WithCSIConfigObserverController(
 		"SharedResourcesDriverCSIConfigObserverController",
@@ -167,124 +185,186 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 		assets.ReadFile,
 		"node.yaml",
 		kubeClient,
-		kubeInformersForNamespaces.InformersFor(defaultNamespace),
+		kubeInformersForNamespaces.InformersFor(config.DefaultNamespace),
 		[]factory.Informer{
 			secretInformer.Informer(),
+			configMapInformer.Informer(),
 		},
-		csidrivernodeservicecontroller.WithSecretHashAnnotationHook(defaultNamespace, metricsCertSecretName, secretInformer),
+		csidrivernodeservicecontroller.WithSecretHashAnnotationHook(config.DefaultNamespace, metricsCertSecretName, secretInformer),
 		csidrivernodeservicecontroller.WithObservedProxyDaemonSetHook(),
+		hooks.WithReservedNamesDaemonSetHook(configMapInformer.Lister().ConfigMaps(config.DefaultNamespace)),
+		hooks.WithNodePlacementDaemonSetHook(configMapInformer.Lister().ConfigMaps(config.DefaultNamespace)),
+		hooks.WithResourcesDaemonSetHook(configMapInformer.Lister().ConfigMaps(config.DefaultNamespace)),
+		hooks.WithLivenessProbeDaemonSetHook(configMapInformer.Lister().ConfigMaps(config.DefaultNamespace)),
+		hooks.WithLivenessProbeSidecarDaemonSetHook(livenessProbeImage),
 	)
 
-	webhookDeploymentController := deploymentcontroller.NewWebHookDeploymentController(
-		kubeClient,
+	crdController, err := crdcontroller.NewCRDController(
+		apiextensionsClient,
 		operatorClient,
-		kubeInformersForNamespaces,
-		configInformers,
+		apiextensionsInformers.Apiextensions().V1().CustomResourceDefinitions(),
 		controllerConfig.EventRecorder,
 	)
+	if err != nil {
+		return err
+	}
 
-	klog.Info("Starting the informers")
-	go kubeInformersForNamespaces.Start(ctx.Done())
-	go dynamicInformers.Start(ctx.Done())
-	go configInformers.Start(ctx.Done())
-	go shareInformersFactory.Start(ctx.Done())
-
-	klog.Info("Starting controllerset")
-	go csiControllerSet.Run(ctx, 1)
-
-	klog.Info("Starting webhookDeploymentController")
-	go webhookDeploymentController.Run(ctx, 1)
-
-	klog.Info("Starting metrics collection")
-
-	klog.Info("Starting metrics endpoint")
-	server := metrics.BuildServer(metrics.MetricsPort)
-	go metrics.RunServer(server, ctx.Done(), kubeconfig)
-
-	<-ctx.Done()
-	crdDone <- true
-	cmDone <- true
-
-	return fmt.Errorf("stopped")
-}
+	driverConfigController := configcontroller.NewDriverConfigController(
+		kubeClient,
+		operatorClient,
+		configMapInformer,
+		controllerConfig.EventRecorder,
+	)
 
-// when we promote out of tech preview and into OCP in general, the shared resource CRDs will be vendored into
-// openshift apiserver and CRD existence will be managed just as it is managed for all the other openshift CRDS;
-// in the interim, this method and the associated ticker created is a "cheap / meets min / don't go down the path
-// of shared informers" means for dealing with inadvertent deletes of the CRD
-func ensureCRDSExist(ctx context.Context, apiextensionsClient apiextensionsclient.Interface) error {
-	crds := []string{"0000_10_sharedsecret.crd.yaml", "0000_10_sharedconfigmap.crd.yaml"}
-	for _, crd := range crds {
-		data, err := assets.ReadFile(crd)
-		if err != nil {
-			return fmt.Errorf("error occurred reading file %q: %s", crd, err)
-		}
+	namespaceLabelController := namespacecontroller.NewNamespaceLabelController(
+		kubeClient,
+		operatorClient,
+		kubeInformersForNamespaces.InformersFor("").Core().V1().Namespaces(),
+		configMapInformer,
+		controllerConfig.EventRecorder,
+	)
 
-		var customResourceDefinition apiextensionsv1.CustomResourceDefinition
-		if err := yaml.Unmarshal(data, &customResourceDefinition); err != nil {
-			return fmt.Errorf("error occurred unmarshalling file %q into object: %s", crd, err)
-		}
+	nodeServiceController := nodecontroller.NewNodeServiceController(
+		operatorClient,
+		kubeInformersForNamespaces.InformersFor(config.DefaultNamespace).Core().V1().Pods(),
+		kubeInformersForNamespaces.InformersFor("").Storage().V1().CSINodes(),
+		controllerConfig.EventRecorder,
+	)
 
-		foundItems, err := apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().List(ctx, metav1.ListOptions{
-			FieldSelector: fmt.Sprintf("metadata.name=%s", customResourceDefinition.Name),
-		})
-		if err != nil {
-			return fmt.Errorf("unexpected error occurred listing CustomResourceDefinitions: %s", err)
-		}
+	entitlementController := entitlementcontroller.NewEntitlementController(
+		kubeClient,
+		shareClient,
+		operatorClient,
+		kubeInformersForNamespaces.InformersFor(entitlementcontroller.EntitlementNamespace).Core().V1().Secrets(),
+		configMapInformer,
+		shareInformersFactory.Sharedresource().V1alpha1().SharedSecrets(),
+		kubeInformersForNamespaces.InformersFor("").Rbac().V1().ClusterRoles(),
+		kubeInformersForNamespaces.InformersFor("").Rbac().V1().ClusterRoleBindings(),
+		controllerConfig.EventRecorder,
+	)
 
-		if len(foundItems.Items) > 0 {
-			klog.Infof("CustomResourceDefinition %q already exists, skipping creation.", customResourceDefinition.Name)
-		} else {
+	grantController := grantcontroller.NewGrantController(
+		kubeClient,
+		shareClient,
+		operatorClient,
+		configMapInformer,
+		kubeInformersForNamespaces.InformersFor("").Core().V1().Namespaces(),
+		shareInformersFactory.Sharedresource().V1alpha1().SharedSecrets(),
+		shareInformersFactory.Sharedresource().V1alpha1().SharedConfigMaps(),
+		grantInformers.Rbac().V1().Roles(),
+		grantInformers.Rbac().V1().RoleBindings(),
+		controllerConfig.EventRecorder,
+	)
 
-			if _, err := apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, &customResourceDefinition, metav1.CreateOptions{}); err != nil {
-				return fmt.Errorf("error occurred creating CustomResourceDefinition: %s", err)
-			}
-			klog.Infof("Successfully created CustomResourceDefinition %q.", customResourceDefinition.Name)
-		}
+	statusController, err := statuscontroller.NewStatusController(
+		shareClient,
+		operatorClient,
+		kubeInformersForNamespaces.InformersFor("").Core().V1().Namespaces(),
+		configMapInformer,
+		shareInformersFactory.Sharedresource().V1alpha1().SharedSecrets(),
+		shareInformersFactory.Sharedresource().V1alpha1().SharedConfigMaps(),
+		metadataInformers.ForResource(corev1.SchemeGroupVersion.WithResource("secrets")),
+		metadataInformers.ForResource(corev1.SchemeGroupVersion.WithResource("configmaps")),
+		controllerConfig.EventRecorder,
+	)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-// the driver will run without our configuration configmap present, but we still prefer to have explicit configuration
-// present, so we employ some cheap / meets min / don't go down the path
-// of shared informers" means for dealing with inadvertent deletes of the configuration configmap
-func ensureConfigurationConfigMapsExists(ctx context.Context, kubeClient kubeclient.Interface) error {
-	cms := []string{"config_configmap.yaml"}
-	for _, cm := range cms {
-		cmData, err := assets.ReadFile(cm)
-		if err != nil {
-			return fmt.Errorf("error occurred reading file %q: %s", cm, err)
-		}
-		configMap := &corev1.ConfigMap{}
-		if err := yaml.Unmarshal(cmData, configMap); err != nil {
-			return fmt.Errorf("error occurred unmarshalling file %q: %s", cm, err)
-		}
-		_, err = kubeClient.CoreV1().ConfigMaps(defaultNamespace).Get(ctx, configMap.Name, metav1.GetOptions{})
-		if err != nil && kerrors.IsNotFound(err) {
-			if _, err = kubeClient.CoreV1().ConfigMaps(defaultNamespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
-				return fmt.Errorf("error occurred creating ConfigMap %q: %s", configMap.Name, err)
+	// the webhook objects are applied to the control plane, which is the managed cluster unless it is hosted
+	webhookFiles := []string{
+		"webhook/sa.yaml",
+		"webhook/configmap.yaml",
+		"webhook/service.yaml",
+	}
+	webhookAssets := assets.ReadFile
+	if len(hostedControlPlaneNamespace) > 0 {
+		webhookFiles = append(webhookFiles, "webhook/hosted_ca_configmap.yaml")
+		webhookAssets = func(name string) ([]byte, error) {
+			data, err := assets.ReadFile(name)
+			if err != nil {
+				return nil, err
 			}
-			klog.Infof("Successfully created ConfigMap %q", configMap.Name)
-		} else if err != nil && !kerrors.IsNotFound(err) {
-			return fmt.Errorf("unexpected error determining if %q exists: %s", configMap.Name, err)
-		} else {
-			klog.Infof("ConfigMap %q already exists, skipping creation", configMap.Name)
+			return bytes.ReplaceAll(data, []byte("namespace: "+config.DefaultNamespace), []byte("namespace: "+hostedControlPlaneNamespace)), nil
 		}
 	}
-	return nil
-}
+	webhookStaticResourcesController := staticresourcecontroller.NewStaticResourceController(
+		"SharedResourcesWebhookStaticResourcesController",
+		webhookAssets,
+		webhookFiles,
+		(&resourceapply.ClientHolder{}).WithKubernetes(controlPlaneKubeClient),
+		operatorClient,
+		controllerConfig.EventRecorder,
+	).AddKubeInformers(controlPlaneInformers)
 
-// setSkipValidationLabelForNamespace sets the label skipValidationLabel for a the defaultNamespace.
-func setSkipValidationLabelForNamespace(ctx context.Context, kubeClient kubeclient.Interface) error {
-	ns, err := kubeClient.CoreV1().Namespaces().Get(ctx, defaultNamespace, metav1.GetOptions{})
+	webhookDeploymentController, err := deploymentcontroller.NewWebHookDeploymentController(
+		controlPlaneKubeClient,
+		operatorClient,
+		kubeInformersForNamespaces,
+		controlPlaneInformers,
+		hostedControlPlaneNamespace,
+		configInformers,
+		controllerConfig.EventRecorder,
+	)
 	if err != nil {
-		return fmt.Errorf("unexpected error determining if %q exists: %s", defaultNamespace, err)
+		return err
 	}
 
-	ns.ObjectMeta.Labels[skipValidationLabel] = "true"
-	_, err = kubeClient.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("unable to update namespace %q with label %q: %s", defaultNamespace, skipValidationLabel, err)
+	// the PodDisruptionBudget follows the replicas of the webhook deployment, see webhookcontroller.NewWebhookPDBController
+	webhookPDBController := webhookcontroller.NewWebhookPDBController(
+		controlPlaneKubeClient,
+		operatorClient,
+		controlPlaneInformers,
+		hostedControlPlaneNamespace,
+		configInformers,
+		controllerConfig.EventRecorder,
+	)
+
+	webhookConfigurationController := webhookcontroller.NewWebhookConfigurationController(
+		kubeClient,
+		operatorClient,
+		configMapInformer,
+		controlPlaneInformers,
+		hostedControlPlaneNamespace,
+		kubeInformersForNamespaces.InformersFor("").Admissionregistration().V1().ValidatingWebhookConfigurations(),
+		controllerConfig.EventRecorder,
+	)
+
+	l := newLifecycle(defaultShutdownGracePeriod)
+	addInformers(l, "operator informers", dynamicInformers)
+	for _, namespace := range sets.List(kubeInformersForNamespaces.Namespaces()) {
+		addInformers(l, fmt.Sprintf("kube informers for namespace %q", namespace), kubeInformersForNamespaces.InformersFor(namespace))
+	}
+	if len(hostedControlPlaneNamespace) > 0 {
+		addInformers(l, "control plane informers", controlPlaneInformers.InformersFor(hostedControlPlaneNamespace))
 	}
-	return nil
+	addInformers(l, "config informers", configInformers)
+	addInformers(l, "apiextensions informers", apiextensionsInformers)
+	addInformers(l, "grant informers", grantInformers)
+	addInformers(l, "metadata informers", metadataInformers)
+
+	// the share informers only sync once crdController has created the CRDs
+	l.addController(crdController.Name(), func(ctx context.Context) { crdController.Run(ctx, 1) })
+	// the controller set runs its controllers in goroutines of its own, which cannot be waited for
+	l.addController("controllerset", func(ctx context.Context) { csiControllerSet.Run(ctx, 1) })
+	l.addController(driverConfigController.Name(), func(ctx context.Context) { driverConfigController.Run(ctx, 1) })
+	l.addController(namespaceLabelController.Name(), func(ctx context.Context) { namespaceLabelController.Run(ctx, 1) })
+	l.addController(nodeServiceController.Name(), func(ctx context.Context) { nodeServiceController.Run(ctx, 1) })
+	l.addController(webhookStaticResourcesController.Name(), func(ctx context.Context) { webhookStaticResourcesController.Run(ctx, 1) })
+	l.addController(webhookDeploymentController.Name(), func(ctx context.Context) { webhookDeploymentController.Run(ctx, 1) })
+	l.addController(webhookPDBController.Name(), func(ctx context.Context) { webhookPDBController.Run(ctx, 1) })
+	l.addController(webhookConfigurationController.Name(), func(ctx context.Context) { webhookConfigurationController.Run(ctx, 1) })
+
+	addInformers(l, "share informers", shareInformersFactory)
+	l.addController(entitlementController.Name(), func(ctx context.Context) { entitlementController.Run(ctx, 1) })
+	l.addController(grantController.Name(), func(ctx context.Context) { grantController.Run(ctx, 1) })
+	l.addController(statusController.Name(), func(ctx context.Context) { statusController.Run(ctx, 1) })
+	l.addFunc("metrics collection", func(ctx context.Context) error {
+		return metrics.InitializeShareCollector(
+			shareInformersFactory.Sharedresource().V1alpha1().SharedSecrets(),
+			shareInformersFactory.Sharedresource().V1alpha1().SharedConfigMaps(),
+			consumerPodInformer,
+		)
+	})
+	return l.Run(ctx)
 }
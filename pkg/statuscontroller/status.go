@@ -0,0 +1,499 @@
+package statuscontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	shareclientv1alpha1 "github.com/openshift/client-go/sharedresource/clientset/versioned"
+	shareinformersv1alpha1 "github.com/openshift/client-go/sharedresource/informers/externalversions/sharedresource/v1alpha1"
+	sharelistersv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+const (
+	controllerName = "SharedResourceStatusController"
+
+	// BackingResourceAvailableConditionType is True when the Secret or ConfigMap referenced by a share exists.
+	BackingResourceAvailableConditionType = "BackingResourceAvailable"
+	// ReservedNameConflictConditionType is True when a share uses a reserved name for a resource other than
+	// the one the name is reserved for; the driver and the webhook refuse such shares.
+	ReservedNameConflictConditionType = "ReservedNameConflict"
+	// ReadyConditionType is True when the share can be mounted.
+	ReadyConditionType = "Ready"
+
+	// StatusDegradedConditionType is reported on the ClusterCSIDriver when share statuses cannot be updated.
+	StatusDegradedConditionType = "ShareStatusDegraded"
+
+	// backingResourceIndex indexes shares by the namespace/name of their backing resource, and
+	// backingNamespaceIndex by its namespace, so that only the events of backing resources reach the controller
+	backingResourceIndex  = "sharedresource.openshift.io/backing-resource"
+	backingNamespaceIndex = "sharedresource.openshift.io/backing-namespace"
+
+	resyncInterval = 10 * time.Minute
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// share is the part of a SharedSecret or SharedConfigMap the status controller works on.
+type share struct {
+	// kind of the backing resource, Secret or ConfigMap
+	kind        string
+	name        string
+	backing     types.NamespacedName
+	annotations map[string]string
+	conditions  []metav1.Condition
+	// updateConditions and updateAnnotations apply mutate to the current conditions or annotations of the share,
+	// retrying on conflicts, so that the changes other controllers make to the share are kept
+	updateConditions  func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error
+	updateAnnotations func(ctx context.Context, mutate func(annotations map[string]string)) error
+	delete            func(ctx context.Context) error
+}
+
+// statusController keeps the BackingResourceAvailable, ReservedNameConflict and Ready conditions of every
+// SharedSecret and SharedConfigMap up to date. The backing Secrets and ConfigMaps are only watched through
+// their metadata, so their content is never cached by the operator.
+//
+// Shares whose backing resource is missing are orphaned. Once they have been orphaned for the configured grace
+// period, the configured OrphanPolicy is applied to them.
+type statusController struct {
+	shareClient           shareclientv1alpha1.Interface
+	operatorClient        v1helpers.OperatorClient
+	namespaceLister       corev1listers.NamespaceLister
+	configMapLister       corev1listers.ConfigMapNamespaceLister
+	sharedSecretLister    sharelistersv1alpha1.SharedSecretLister
+	sharedConfigMapLister sharelistersv1alpha1.SharedConfigMapLister
+	secretMetadataLister  cache.GenericLister
+	cmMetadataLister      cache.GenericLister
+}
+
+func NewStatusController(shareClient shareclientv1alpha1.Interface,
+	operatorClient v1helpers.OperatorClient,
+	namespaceInformer corev1informers.NamespaceInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	sharedSecretInformer shareinformersv1alpha1.SharedSecretInformer,
+	sharedConfigMapInformer shareinformersv1alpha1.SharedConfigMapInformer,
+	secretMetadataInformer informers.GenericInformer,
+	cmMetadataInformer informers.GenericInformer,
+	recorder events.Recorder) (factory.Controller, error) {
+
+	secretIndexer, err := addBackingIndexers(sharedSecretInformer.Informer(), func(obj interface{}) (types.NamespacedName, bool) {
+		s, ok := obj.(*sharev1alpha1.SharedSecret)
+		if !ok {
+			return types.NamespacedName{}, false
+		}
+		return types.NamespacedName{Namespace: s.Spec.SecretRef.Namespace, Name: s.Spec.SecretRef.Name}, true
+	})
+	if err != nil {
+		return nil, err
+	}
+	cmIndexer, err := addBackingIndexers(sharedConfigMapInformer.Informer(), func(obj interface{}) (types.NamespacedName, bool) {
+		s, ok := obj.(*sharev1alpha1.SharedConfigMap)
+		if !ok {
+			return types.NamespacedName{}, false
+		}
+		return types.NamespacedName{Namespace: s.Spec.ConfigMapRef.Namespace, Name: s.Spec.ConfigMapRef.Name}, true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &statusController{
+		shareClient:           shareClient,
+		operatorClient:        operatorClient,
+		namespaceLister:       namespaceInformer.Lister(),
+		configMapLister:       configMapInformer.Lister().ConfigMaps(config.DefaultNamespace),
+		sharedSecretLister:    sharedSecretInformer.Lister(),
+		sharedConfigMapLister: sharedConfigMapInformer.Lister(),
+		secretMetadataLister:  secretMetadataInformer.Lister(),
+		cmMetadataLister:      cmMetadataInformer.Lister(),
+	}
+	return factory.New().WithInformers(
+		sharedSecretInformer.Informer(),
+		sharedConfigMapInformer.Informer(),
+	).WithFilteredEventsInformers(
+		isBacking(backingResourceIndex, secretIndexer),
+		secretMetadataInformer.Informer(),
+	).WithFilteredEventsInformers(
+		isBacking(backingResourceIndex, cmIndexer),
+		cmMetadataInformer.Informer(),
+	).WithFilteredEventsInformers(
+		func(obj interface{}) bool {
+			return isBacking(backingNamespaceIndex, secretIndexer)(obj) || isBacking(backingNamespaceIndex, cmIndexer)(obj)
+		},
+		namespaceInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(config.OperatorConfigMapName),
+		configMapInformer.Informer(),
+	).WithSync(
+		c.sync,
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		recorder.WithComponentSuffix("shared-resource-status-controller"),
+	), nil
+}
+
+// addBackingIndexers indexes the shares of informer by the backing resource backing returns for them, and returns
+// the indexer of informer.
+func addBackingIndexers(informer cache.SharedIndexInformer, backing func(obj interface{}) (types.NamespacedName, bool)) (cache.Indexer, error) {
+	err := informer.AddIndexers(cache.Indexers{
+		backingResourceIndex: func(obj interface{}) ([]string, error) {
+			if name, ok := backing(obj); ok {
+				return []string{name.String()}, nil
+			}
+			return nil, nil
+		},
+		backingNamespaceIndex: func(obj interface{}) ([]string, error) {
+			if name, ok := backing(obj); ok {
+				return []string{name.Namespace}, nil
+			}
+			return nil, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return informer.GetIndexer(), nil
+}
+
+// isBacking returns an event filter accepting the objects that back a share of indexer: Secrets or ConfigMaps
+// under backingResourceIndex, namespaces under backingNamespaceIndex.
+func isBacking(index string, indexer cache.Indexer) factory.EventFilterFunc {
+	return func(obj interface{}) bool {
+		object, ok := obj.(metav1.Object)
+		if !ok {
+			// tombstones and other unexpected objects are cheap enough to sync on
+			return true
+		}
+		key := object.GetName()
+		if index == backingResourceIndex {
+			key = types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}.String()
+		}
+		shares, err := indexer.ByIndex(index, key)
+		return err != nil || len(shares) > 0
+	}
+}
+
+func (c *statusController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	opConfig, err := config.GetOperatorConfig(c.configMapLister)
+	if err != nil {
+		// the configuration error is reported by the driver config controller, the default reservations still apply
+		opConfig = &config.OperatorConfig{}
+	}
+	shares, err := c.listShares()
+	if err != nil {
+		return err
+	}
+	reserved := map[string]map[string]types.NamespacedName{
+		"Secret":    opConfig.GetReservedSharedSecretNames(),
+		"ConfigMap": opConfig.GetReservedSharedConfigMapNames(),
+	}
+
+	var errs []error
+	for _, s := range shares {
+		conditions, err := c.conditions(s, reserved[s.kind])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		wasOrphaned := meta.IsStatusConditionFalse(s.conditions, BackingResourceAvailableConditionType)
+		if err := setConditions(ctx, s, conditions); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := c.handleOrphan(ctx, syncContext, s, wasOrphaned, opConfig); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	syncErr := v1helpers.NewMultiLineAggregate(errs)
+	if err := c.updateCondition(ctx, syncErr); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+func (c *statusController) listShares() ([]*share, error) {
+	sharedSecrets, err := c.sharedSecretLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sharedConfigMaps, err := c.sharedConfigMapLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*share, 0, len(sharedSecrets)+len(sharedConfigMaps))
+	for _, s := range sharedSecrets {
+		s := s
+		shares = append(shares, &share{
+			kind:        "Secret",
+			name:        s.Name,
+			backing:     types.NamespacedName{Namespace: s.Spec.SecretRef.Namespace, Name: s.Spec.SecretRef.Name},
+			annotations: s.Annotations,
+			conditions:  s.Status.Conditions,
+			updateConditions: func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					current, err := c.shareClient.SharedresourceV1alpha1().SharedSecrets().Get(ctx, s.Name, metav1.GetOptions{})
+					if kerrors.IsNotFound(err) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if !mutateConditions(&current.Status.Conditions, mutate) {
+						return nil
+					}
+					_, err = c.shareClient.SharedresourceV1alpha1().SharedSecrets().UpdateStatus(ctx, current, metav1.UpdateOptions{})
+					return err
+				})
+			},
+			updateAnnotations: func(ctx context.Context, mutate func(annotations map[string]string)) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					current, err := c.shareClient.SharedresourceV1alpha1().SharedSecrets().Get(ctx, s.Name, metav1.GetOptions{})
+					if kerrors.IsNotFound(err) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if current.Annotations == nil {
+						current.Annotations = map[string]string{}
+					}
+					mutate(current.Annotations)
+					_, err = c.shareClient.SharedresourceV1alpha1().SharedSecrets().Update(ctx, current, metav1.UpdateOptions{})
+					return err
+				})
+			},
+			delete: func(ctx context.Context) error {
+				return c.shareClient.SharedresourceV1alpha1().SharedSecrets().Delete(ctx, s.Name, metav1.DeleteOptions{})
+			},
+		})
+	}
+	for _, s := range sharedConfigMaps {
+		s := s
+		shares = append(shares, &share{
+			kind:        "ConfigMap",
+			name:        s.Name,
+			backing:     types.NamespacedName{Namespace: s.Spec.ConfigMapRef.Namespace, Name: s.Spec.ConfigMapRef.Name},
+			annotations: s.Annotations,
+			conditions:  s.Status.Conditions,
+			updateConditions: func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					current, err := c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().Get(ctx, s.Name, metav1.GetOptions{})
+					if kerrors.IsNotFound(err) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if !mutateConditions(&current.Status.Conditions, mutate) {
+						return nil
+					}
+					_, err = c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().UpdateStatus(ctx, current, metav1.UpdateOptions{})
+					return err
+				})
+			},
+			updateAnnotations: func(ctx context.Context, mutate func(annotations map[string]string)) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					current, err := c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().Get(ctx, s.Name, metav1.GetOptions{})
+					if kerrors.IsNotFound(err) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if current.Annotations == nil {
+						current.Annotations = map[string]string{}
+					}
+					mutate(current.Annotations)
+					_, err = c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().Update(ctx, current, metav1.UpdateOptions{})
+					return err
+				})
+			},
+			delete: func(ctx context.Context) error {
+				return c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().Delete(ctx, s.Name, metav1.DeleteOptions{})
+			},
+		})
+	}
+	return shares, nil
+}
+
+// conditions computes the conditions of s given the share names reserved for its kind of backing resource.
+func (c *statusController) conditions(s *share, reserved map[string]types.NamespacedName) ([]metav1.Condition, error) {
+	available := metav1.Condition{
+		Type:    BackingResourceAvailableConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Found",
+		Message: fmt.Sprintf("%s %s exists", s.kind, s.backing),
+	}
+	exists, err := c.backingResourceExists(s)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		available.Status = metav1.ConditionFalse
+		available.Reason = "NotFound"
+		available.Message = fmt.Sprintf("%s %s does not exist", s.kind, s.backing)
+		_, err := c.namespaceLister.Get(s.backing.Namespace)
+		if kerrors.IsNotFound(err) {
+			available.Reason = "NamespaceNotFound"
+			available.Message = fmt.Sprintf("namespace %s of %s %s does not exist", s.backing.Namespace, s.kind, s.backing)
+		}
+	}
+
+	conflict := metav1.Condition{
+		Type:   ReservedNameConflictConditionType,
+		Status: metav1.ConditionFalse,
+		Reason: "NoConflict",
+	}
+	if target, ok := reserved[s.name]; ok && target != s.backing {
+		conflict.Status = metav1.ConditionTrue
+		conflict.Reason = "ReservedName"
+		conflict.Message = fmt.Sprintf("name %s is reserved for %s %s", s.name, s.kind, target)
+	}
+
+	ready := metav1.Condition{
+		Type:   ReadyConditionType,
+		Status: metav1.ConditionTrue,
+		Reason: "Ready",
+	}
+	switch {
+	case conflict.Status == metav1.ConditionTrue:
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = "ReservedNameConflict"
+		ready.Message = conflict.Message
+	case available.Status == metav1.ConditionFalse:
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = "BackingResourceUnavailable"
+		ready.Message = available.Message
+	}
+	return []metav1.Condition{available, conflict, ready}, nil
+}
+
+func (c *statusController) backingResourceExists(s *share) (bool, error) {
+	lister := c.secretMetadataLister
+	if s.kind == "ConfigMap" {
+		lister = c.cmMetadataLister
+	}
+	_, err := lister.ByNamespace(s.backing.Namespace).Get(s.backing.Name)
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unexpected error determining if %s %s exists: %s", s.kind, s.backing, err)
+	}
+	return true, nil
+}
+
+// setConditions merges conditions into those of s, updating the share status only when they change.
+func setConditions(ctx context.Context, s *share, conditions []metav1.Condition) error {
+	mutate := func(current *[]metav1.Condition) {
+		for _, condition := range conditions {
+			meta.SetStatusCondition(current, condition)
+		}
+	}
+	updated := append([]metav1.Condition(nil), s.conditions...)
+	if !mutateConditions(&updated, mutate) {
+		return nil
+	}
+	if err := s.updateConditions(ctx, mutate); err != nil {
+		return fmt.Errorf("unable to update the status of share %s: %s", s.name, err)
+	}
+	s.conditions = updated
+	return nil
+}
+
+// mutateConditions applies mutate to conditions and returns whether they changed.
+func mutateConditions(conditions *[]metav1.Condition, mutate func(conditions *[]metav1.Condition)) bool {
+	original := append([]metav1.Condition(nil), *conditions...)
+	mutate(conditions)
+	return !equality.Semantic.DeepEqual(original, *conditions)
+}
+
+// handleOrphan reports s becoming orphaned or being restored, and applies the configured OrphanPolicy once s
+// has been orphaned for the grace period. The conditions of s must be up to date.
+func (c *statusController) handleOrphan(ctx context.Context, syncContext factory.SyncContext, s *share, wasOrphaned bool, opConfig *config.OperatorConfig) error {
+	available := meta.FindStatusCondition(s.conditions, BackingResourceAvailableConditionType)
+	if available.Status == metav1.ConditionTrue {
+		if wasOrphaned {
+			syncContext.Recorder().Eventf("ShareRestored", "%s %s of share %s exists again", s.kind, s.backing, s.name)
+		}
+		if _, ok := s.annotations[config.OrphanedAnnotation]; !ok {
+			return nil
+		}
+		if err := s.updateAnnotations(ctx, func(annotations map[string]string) {
+			delete(annotations, config.OrphanedAnnotation)
+		}); err != nil {
+			return fmt.Errorf("unable to remove annotation %s from share %s: %s", config.OrphanedAnnotation, s.name, err)
+		}
+		return nil
+	}
+
+	if !wasOrphaned {
+		syncContext.Recorder().Warningf("ShareOrphaned", "share %s is orphaned: %s", s.name, available.Message)
+	}
+	policy := opConfig.GetOrphanPolicy()
+	if policy == config.OrphanPolicyIgnore {
+		return nil
+	}
+	if remaining := available.LastTransitionTime.Add(opConfig.GetOrphanGracePeriod()).Sub(now()); remaining > 0 {
+		syncContext.Queue().AddAfter(syncContext.QueueKey(), remaining)
+		return nil
+	}
+
+	switch policy {
+	case config.OrphanPolicyDelete:
+		if err := s.delete(ctx); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("unable to delete orphaned share %s: %s", s.name, err)
+		}
+		syncContext.Recorder().Eventf("OrphanedShareDeleted", "deleted share %s: %s", s.name, available.Message)
+	case config.OrphanPolicyAnnotate:
+		since := available.LastTransitionTime.UTC().Format(time.RFC3339)
+		if s.annotations[config.OrphanedAnnotation] == since {
+			return nil
+		}
+		if err := s.updateAnnotations(ctx, func(annotations map[string]string) {
+			annotations[config.OrphanedAnnotation] = since
+		}); err != nil {
+			return fmt.Errorf("unable to annotate orphaned share %s: %s", s.name, err)
+		}
+		syncContext.Recorder().Eventf("OrphanedShareAnnotated", "annotated share %s with %s: %s", s.name, config.OrphanedAnnotation, available.Message)
+	}
+	return nil
+}
+
+func (c *statusController) updateCondition(ctx context.Context, err error) error {
+	condition := operatorv1.OperatorCondition{
+		Type:   StatusDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if err != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = "SyncFailed"
+		condition.Message = err.Error()
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return updateErr
+}
@@ -0,0 +1,389 @@
+package statuscontroller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	sharefake "github.com/openshift/client-go/sharedresource/clientset/versioned/fake"
+	sharelistersv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func metadata(namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+func sharedSecret(name, namespace, secret string) *sharev1alpha1.SharedSecret {
+	return &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{Name: secret, Namespace: namespace},
+		},
+	}
+}
+
+// newController returns a statusController, and the client it updates share through, for a cluster holding share,
+// the source namespace and its Secret source/secret.
+func newController(share *sharev1alpha1.SharedSecret, operatorConfig string) (*statusController, *sharefake.Clientset) {
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nsIndexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "source"}})
+	secretIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	secretIndexer.Add(metadata("source", "secret"))
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if len(operatorConfig) > 0 {
+		cmIndexer.Add(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: config.OperatorConfigMapName, Namespace: config.DefaultNamespace},
+			Data:       map[string]string{config.ConfigKey: operatorConfig},
+		})
+	}
+	shareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	shareIndexer.Add(share)
+
+	shareClient := sharefake.NewSimpleClientset(share)
+	return &statusController{
+		shareClient:           shareClient,
+		operatorClient:        v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil),
+		namespaceLister:       corev1listers.NewNamespaceLister(nsIndexer),
+		configMapLister:       corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+		sharedSecretLister:    sharelistersv1alpha1.NewSharedSecretLister(shareIndexer),
+		sharedConfigMapLister: sharelistersv1alpha1.NewSharedConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		secretMetadataLister:  cache.NewGenericLister(secretIndexer, schema.GroupResource{Resource: "secrets"}),
+		cmMetadataLister:      cache.NewGenericLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}), schema.GroupResource{Resource: "configmaps"}),
+	}, shareClient
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		share           *sharev1alpha1.SharedSecret
+		operatorConfig  string
+		expectAvailable metav1.ConditionStatus
+		expectReason    string
+		expectConflict  metav1.ConditionStatus
+		expectReady     metav1.ConditionStatus
+		expectReadyMsg  string
+	}{
+		{
+			name:            "backing Secret exists",
+			share:           sharedSecret("my-share", "source", "secret"),
+			expectAvailable: metav1.ConditionTrue,
+			expectReason:    "Found",
+			expectConflict:  metav1.ConditionFalse,
+			expectReady:     metav1.ConditionTrue,
+		},
+		{
+			name:            "backing Secret is missing",
+			share:           sharedSecret("my-share", "source", "deleted"),
+			expectAvailable: metav1.ConditionFalse,
+			expectReason:    "NotFound",
+			expectConflict:  metav1.ConditionFalse,
+			expectReady:     metav1.ConditionFalse,
+			expectReadyMsg:  "Secret source/deleted does not exist",
+		},
+		{
+			name:            "backing namespace is missing",
+			share:           sharedSecret("my-share", "deleted", "secret"),
+			expectAvailable: metav1.ConditionFalse,
+			expectReason:    "NamespaceNotFound",
+			expectConflict:  metav1.ConditionFalse,
+			expectReady:     metav1.ConditionFalse,
+			expectReadyMsg:  "namespace deleted of Secret deleted/secret does not exist",
+		},
+		{
+			name:            "default reserved name used for another Secret",
+			share:           sharedSecret("openshift-etc-pki-entitlement", "source", "secret"),
+			expectAvailable: metav1.ConditionTrue,
+			expectReason:    "Found",
+			expectConflict:  metav1.ConditionTrue,
+			expectReady:     metav1.ConditionFalse,
+			expectReadyMsg:  "name openshift-etc-pki-entitlement is reserved for Secret openshift-config-managed/etc-pki-entitlement",
+		},
+		{
+			name:            "configured reserved name used for its Secret",
+			share:           sharedSecret("my-reserved-share", "source", "secret"),
+			operatorConfig:  "reservedSharedSecretNames:\n  my-reserved-share: source:secret\n",
+			expectAvailable: metav1.ConditionTrue,
+			expectReason:    "Found",
+			expectConflict:  metav1.ConditionFalse,
+			expectReady:     metav1.ConditionTrue,
+		},
+	} {
+		c, shareClient := newController(test.share, test.operatorConfig)
+
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, events.NewInMemoryRecorder(controllerName))); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), test.share.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		available := meta.FindStatusCondition(updated.Status.Conditions, BackingResourceAvailableConditionType)
+		if available == nil || available.Status != test.expectAvailable || available.Reason != test.expectReason {
+			t.Errorf("testcase %s: expected condition %s %s/%s, got %v", test.name, BackingResourceAvailableConditionType, test.expectAvailable, test.expectReason, available)
+		}
+		if !meta.IsStatusConditionPresentAndEqual(updated.Status.Conditions, ReservedNameConflictConditionType, test.expectConflict) {
+			t.Errorf("testcase %s: expected condition %s to be %s, got %v", test.name, ReservedNameConflictConditionType, test.expectConflict, updated.Status.Conditions)
+		}
+		ready := meta.FindStatusCondition(updated.Status.Conditions, ReadyConditionType)
+		if ready == nil || ready.Status != test.expectReady || ready.Message != test.expectReadyMsg {
+			t.Errorf("testcase %s: expected condition %s %s %q, got %v", test.name, ReadyConditionType, test.expectReady, test.expectReadyMsg, ready)
+		}
+	}
+}
+
+func TestOrphanPolicy(t *testing.T) {
+	orphaned := func(since time.Time, annotations map[string]string) *sharev1alpha1.SharedSecret {
+		s := sharedSecret("my-share", "source", "deleted")
+		s.Annotations = annotations
+		s.Status.Conditions = []metav1.Condition{{
+			Type:               BackingResourceAvailableConditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             "NotFound",
+			Message:            "Secret source/deleted does not exist",
+			LastTransitionTime: metav1.NewTime(since),
+		}}
+		return s
+	}
+	twoHoursAgo := now().Add(-2 * time.Hour)
+
+	for _, test := range []struct {
+		name              string
+		share             *sharev1alpha1.SharedSecret
+		operatorConfig    string
+		expectDeleted     bool
+		expectAnnotations map[string]string
+		expectEvents      []string
+	}{
+		{
+			name:         "newly orphaned share is reported",
+			share:        sharedSecret("my-share", "source", "deleted"),
+			expectEvents: []string{"ShareOrphaned"},
+		},
+		{
+			name:           "orphaned share is kept during the grace period",
+			share:          orphaned(now().Add(-30*time.Minute), nil),
+			operatorConfig: "orphanedShares:\n  policy: Delete\n",
+		},
+		{
+			name:           "orphaned share is deleted after the grace period",
+			share:          orphaned(twoHoursAgo, nil),
+			operatorConfig: "orphanedShares:\n  policy: Delete\n",
+			expectDeleted:  true,
+			expectEvents:   []string{"OrphanedShareDeleted"},
+		},
+		{
+			name:              "orphaned share is annotated after a configured grace period",
+			share:             orphaned(twoHoursAgo, map[string]string{"team": "builds"}),
+			operatorConfig:    "orphanedShares:\n  policy: Annotate\n  gracePeriod: 90m\n",
+			expectAnnotations: map[string]string{"team": "builds", config.OrphanedAnnotation: twoHoursAgo.UTC().Format(time.RFC3339)},
+			expectEvents:      []string{"OrphanedShareAnnotated"},
+		},
+		{
+			name: "restored share loses the annotation",
+			share: func() *sharev1alpha1.SharedSecret {
+				s := orphaned(twoHoursAgo, map[string]string{config.OrphanedAnnotation: twoHoursAgo.UTC().Format(time.RFC3339)})
+				s.Spec.SecretRef.Name = "secret"
+				return s
+			}(),
+			operatorConfig:    "orphanedShares:\n  policy: Annotate\n",
+			expectAnnotations: map[string]string{},
+			expectEvents:      []string{"ShareRestored"},
+		},
+	} {
+		c, shareClient := newController(test.share, test.operatorConfig)
+		recorder := events.NewInMemoryRecorder(controllerName)
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, recorder)); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		reasons := []string{}
+		for _, event := range recorder.Events() {
+			reasons = append(reasons, event.Reason)
+		}
+		if strings.Join(reasons, ",") != strings.Join(test.expectEvents, ",") {
+			t.Errorf("testcase %s: expected events %v, got %v", test.name, test.expectEvents, reasons)
+		}
+
+		updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), test.share.Name, metav1.GetOptions{})
+		if test.expectDeleted {
+			if !kerrors.IsNotFound(err) {
+				t.Errorf("testcase %s: expected the share to be deleted, got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		if test.expectAnnotations == nil {
+			if !equality.Semantic.DeepEqual(updated.Annotations, test.share.Annotations) {
+				t.Errorf("testcase %s: expected annotations %v, got %v", test.name, test.share.Annotations, updated.Annotations)
+			}
+			continue
+		}
+		if len(updated.Annotations) != len(test.expectAnnotations) {
+			t.Errorf("testcase %s: expected annotations %v, got %v", test.name, test.expectAnnotations, updated.Annotations)
+		}
+		for k, v := range test.expectAnnotations {
+			if updated.Annotations[k] != v {
+				t.Errorf("testcase %s: expected annotations %v, got %v", test.name, test.expectAnnotations, updated.Annotations)
+			}
+		}
+	}
+}
+
+func TestSetConditionsUnchanged(t *testing.T) {
+	conditions := []metav1.Condition{{Type: ReadyConditionType, Status: metav1.ConditionTrue, Reason: "Ready"}}
+	s := &share{
+		name:       "my-share",
+		conditions: conditions,
+		updateConditions: func(ctx context.Context, mutate func(conditions *[]metav1.Condition)) error {
+			t.Errorf("unexpected status update of %v", conditions)
+			return nil
+		},
+	}
+	if err := setConditions(context.TODO(), s, []metav1.Condition{{Type: ReadyConditionType, Status: metav1.ConditionTrue, Reason: "Ready"}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSetConditionsKeepsOtherConditions(t *testing.T) {
+	// the lister has not seen the AccessGranted condition another controller has just set
+	cached := sharedSecret("my-share", "source", "secret")
+	c, shareClient := newController(cached, "")
+	current := cached.DeepCopy()
+	current.ResourceVersion = "2"
+	current.Status.Conditions = []metav1.Condition{{Type: "AccessGranted", Status: metav1.ConditionTrue, Reason: "Granted"}}
+	if _, err := shareClient.SharedresourceV1alpha1().SharedSecrets().UpdateStatus(context.TODO(), current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, events.NewInMemoryRecorder(controllerName))); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), "my-share", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, conditionType := range []string{"AccessGranted", BackingResourceAvailableConditionType, ReadyConditionType} {
+		if meta.FindStatusCondition(updated.Status.Conditions, conditionType) == nil {
+			t.Errorf("expected condition %s, got %v", conditionType, updated.Status.Conditions)
+		}
+	}
+}
+
+func TestBackingEventFilters(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &sharev1alpha1.SharedSecret{}, 0, cache.Indexers{})
+	indexer, err := addBackingIndexers(informer, func(obj interface{}) (types.NamespacedName, bool) {
+		s, ok := obj.(*sharev1alpha1.SharedSecret)
+		if !ok {
+			return types.NamespacedName{}, false
+		}
+		return types.NamespacedName{Namespace: s.Spec.SecretRef.Namespace, Name: s.Spec.SecretRef.Name}, true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	indexer.Add(sharedSecret("my-share", "source", "secret"))
+
+	for _, test := range []struct {
+		name   string
+		index  string
+		obj    interface{}
+		expect bool
+	}{
+		{"backing Secret", backingResourceIndex, metadata("source", "secret"), true},
+		{"other Secret of the backing namespace", backingResourceIndex, metadata("source", "other"), false},
+		{"Secret of another namespace", backingResourceIndex, metadata("elsewhere", "secret"), false},
+		{"backing namespace", backingNamespaceIndex, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "source"}}, true},
+		{"other namespace", backingNamespaceIndex, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "elsewhere"}}, false},
+		{"tombstone", backingResourceIndex, cache.DeletedFinalStateUnknown{Key: "elsewhere/secret"}, true},
+	} {
+		if accepted := isBacking(test.index, indexer)(test.obj); accepted != test.expect {
+			t.Errorf("testcase %s: expected the event to be accepted %v, got %v", test.name, test.expect, accepted)
+		}
+	}
+}
+
+// TestOrphanGracePeriodAfterRestart checks that the grace period of the Delete policy is measured from the
+// persisted condition, so that a restarted operator syncing repeatedly does not delete shares early.
+func TestOrphanGracePeriodAfterRestart(t *testing.T) {
+	orphanedSince := metav1.NewTime(now().Add(-30 * time.Minute).Truncate(time.Second))
+	for _, test := range []struct {
+		name        string
+		conditions  []metav1.Condition
+		expectSince *metav1.Time
+	}{
+		{
+			name: "orphaned before the restart",
+			conditions: []metav1.Condition{{
+				Type:               BackingResourceAvailableConditionType,
+				Status:             metav1.ConditionFalse,
+				Reason:             "NotFound",
+				Message:            "Secret source/deleted does not exist",
+				LastTransitionTime: orphanedSince,
+			}},
+			expectSince: &orphanedSince,
+		},
+		{
+			name: "orphaned while the operator was down",
+			conditions: []metav1.Condition{{
+				Type:               BackingResourceAvailableConditionType,
+				Status:             metav1.ConditionTrue,
+				Reason:             "Found",
+				LastTransitionTime: metav1.NewTime(now().Add(-48 * time.Hour)),
+			}},
+		},
+	} {
+		s := sharedSecret("my-share", "source", "deleted")
+		s.Status.Conditions = test.conditions
+		// every sync stands for an operator restart, or an event of another object
+		for i := 0; i < 3; i++ {
+			c, shareClient := newController(s, "orphanedShares:\n  policy: Delete\n")
+			recorder := events.NewInMemoryRecorder(controllerName)
+			if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, recorder)); err != nil {
+				t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+			}
+			for _, event := range recorder.Events() {
+				if event.Reason == "OrphanedShareDeleted" {
+					t.Fatalf("testcase %s: sync %d: share deleted within the grace period", test.name, i)
+				}
+			}
+			updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), s.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("testcase %s: sync %d: expected the share to be kept, got %v", test.name, i, err)
+			}
+			available := meta.FindStatusCondition(updated.Status.Conditions, BackingResourceAvailableConditionType)
+			if available == nil || available.Status != metav1.ConditionFalse {
+				t.Fatalf("testcase %s: sync %d: expected the share to be orphaned, got %v", test.name, i, available)
+			}
+			if test.expectSince != nil && !available.LastTransitionTime.Equal(test.expectSince) {
+				t.Errorf("testcase %s: sync %d: expected the grace period to start at %v, got %v", test.name, i, test.expectSince, available.LastTransitionTime)
+			}
+			if test.expectSince == nil && now().Sub(available.LastTransitionTime.Time) > time.Minute {
+				t.Errorf("testcase %s: sync %d: expected the grace period to start now, got %v", test.name, i, available.LastTransitionTime)
+			}
+			// the next start sees the status persisted by this one
+			s = updated
+		}
+	}
+}
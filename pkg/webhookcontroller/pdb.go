@@ -0,0 +1,172 @@
+package webhookcontroller
+
+import (
+	"context"
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	policyv1listers "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/klog/v2"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configinformers "github.com/openshift/client-go/config/informers/externalversions"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+const (
+	pdbControllerName = "SharedResourceWebhookPDBController"
+	infraConfigName   = "cluster"
+
+	// WebhookPDBDegradedConditionType is reported on the ClusterCSIDriver when the PodDisruptionBudget of the
+	// webhook cannot be applied.
+	WebhookPDBDegradedConditionType = "WebhookPDBDegraded"
+)
+
+// webhookPDBController keeps the PodDisruptionBudget of the webhook in line with the replicas of its Deployment.
+// A single replica, which is all a SingleReplica control plane gets, cannot be protected without blocking node
+// drains, so the budget is deleted; with more replicas a fixed maxUnavailable of 1 lets one of them be disrupted at
+// a time.
+type webhookPDBController struct {
+	kubeClient       kubernetes.Interface
+	operatorClient   v1helpers.OperatorClient
+	deploymentLister appsv1listers.DeploymentNamespaceLister
+	pdbLister        policyv1listers.PodDisruptionBudgetNamespaceLister
+	infraLister      configv1listers.InfrastructureLister
+	required         *policyv1.PodDisruptionBudget
+}
+
+// NewWebhookPDBController manages the PodDisruptionBudget of the webhook through kubeClient, in the namespace the
+// webhook runs in: hostedControlPlaneNamespace of the management cluster when it is set.
+func NewWebhookPDBController(kubeClient kubernetes.Interface,
+	operatorClient v1helpers.OperatorClient,
+	controlPlaneInformers v1helpers.KubeInformersForNamespaces,
+	hostedControlPlaneNamespace string,
+	configInformer configinformers.SharedInformerFactory,
+	recorder events.Recorder) factory.Controller {
+
+	namespace := config.DefaultNamespace
+	if len(hostedControlPlaneNamespace) > 0 {
+		namespace = hostedControlPlaneNamespace
+	}
+	deploymentInformer := controlPlaneInformers.InformersFor(namespace).Apps().V1().Deployments()
+	pdbInformer := controlPlaneInformers.InformersFor(namespace).Policy().V1().PodDisruptionBudgets()
+	infraInformer := configInformer.Config().V1().Infrastructures()
+
+	required := resourceread.ReadPodDisruptionBudgetV1OrDie(assets.MustAsset("webhook/pdb.yaml"))
+	required.Namespace = namespace
+	c := &webhookPDBController{
+		kubeClient:       kubeClient,
+		operatorClient:   operatorClient,
+		deploymentLister: deploymentInformer.Lister().Deployments(namespace),
+		pdbLister:        pdbInformer.Lister().PodDisruptionBudgets(namespace),
+		infraLister:      infraInformer.Lister(),
+		required:         required,
+	}
+	return factory.New().WithFilteredEventsInformers(
+		factory.NamesFilter(webhookName),
+		deploymentInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(required.Name),
+		pdbInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(infraConfigName),
+		infraInformer.Informer(),
+	).WithSync(
+		c.sync,
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		pdbControllerName,
+		recorder.WithComponentSuffix("shared-resource-webhook-pdb-controller"),
+	)
+}
+
+func (c *webhookPDBController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	syncErr := c.syncPDB(ctx, syncContext.Recorder())
+
+	condition := operatorv1.OperatorCondition{
+		Type:   WebhookPDBDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if syncErr != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = "SyncFailed"
+		condition.Message = syncErr.Error()
+	}
+	if _, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition)); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+func (c *webhookPDBController) syncPDB(ctx context.Context, recorder events.Recorder) error {
+	deployment, err := c.deploymentLister.Get(webhookName)
+	if kerrors.IsNotFound(err) {
+		// synced again once the webhook deployment controller has created it
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unexpected error determining if %q exists: %s", webhookName, err)
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	infra, err := c.infraLister.Get(infraConfigName)
+	if err != nil {
+		return fmt.Errorf("unable to get Infrastructure %q: %s", infraConfigName, err)
+	}
+
+	required := c.requiredPDB(replicas, infra.Status.ControlPlaneTopology)
+	existing, err := c.pdbLister.Get(c.required.Name)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("unexpected error determining if %q exists: %s", c.required.Name, err)
+	}
+	exists := err == nil
+
+	if required == nil {
+		if !exists {
+			return nil
+		}
+		klog.Infof("Deleting PodDisruptionBudget %s/%s of the webhook running %d replica(s) in a %s control plane", c.required.Namespace, c.required.Name, replicas, infra.Status.ControlPlaneTopology)
+		if _, _, err := resourceapply.DeletePodDisruptionBudget(ctx, c.kubeClient.PolicyV1(), recorder, c.required); err != nil {
+			return fmt.Errorf("error deleting PodDisruptionBudget %q: %w", c.required.Name, err)
+		}
+		return nil
+	}
+	if exists && equality.Semantic.DeepEqual(existing.Spec, required.Spec) {
+		return nil
+	}
+	if _, _, err := resourceapply.ApplyPodDisruptionBudget(ctx, c.kubeClient.PolicyV1(), recorder, required); err != nil {
+		return fmt.Errorf("error applying PodDisruptionBudget %q: %w", required.Name, err)
+	}
+	return nil
+}
+
+// requiredPDB returns the PodDisruptionBudget for a webhook running replicas in a control plane of topology, nil
+// when it should have none. The replica count only decides whether there is a budget: maxUnavailable is always 1,
+// whatever the number of replicas.
+func (c *webhookPDBController) requiredPDB(replicas int32, topology configv1.TopologyMode) *policyv1.PodDisruptionBudget {
+	if replicas < 2 || topology == configv1.SingleReplicaTopologyMode {
+		return nil
+	}
+	required := c.required.DeepCopy()
+	maxUnavailable := intstr.FromInt32(1)
+	required.Spec.MinAvailable = nil
+	required.Spec.MaxUnavailable = &maxUnavailable
+	return required
+}
@@ -0,0 +1,148 @@
+package webhookcontroller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	policyv1listers "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func TestSyncPDB(t *testing.T) {
+	replicas := func(n int32) *appsv1.Deployment {
+		deployment := webhookDeployment(n)
+		deployment.Spec.Replicas = &n
+		return deployment
+	}
+	livePDB := func(minAvailable int) *policyv1.PodDisruptionBudget {
+		pdb := resourceread.ReadPodDisruptionBudgetV1OrDie(assets.MustAsset("webhook/pdb.yaml"))
+		available := intstr.FromInt(minAvailable)
+		pdb.Spec.MaxUnavailable = nil
+		pdb.Spec.MinAvailable = &available
+		return pdb
+	}
+
+	for _, test := range []struct {
+		name           string
+		deployment     *appsv1.Deployment
+		topology       configv1.TopologyMode
+		live           *policyv1.PodDisruptionBudget
+		expectPDB      bool
+		expectDegraded bool
+	}{
+		{
+			name:     "no deployment yet",
+			topology: configv1.HighlyAvailableTopologyMode,
+		},
+		{
+			name:       "single replica",
+			deployment: replicas(1),
+			topology:   configv1.HighlyAvailableTopologyMode,
+		},
+		{
+			name:       "single replica deletes budget",
+			deployment: replicas(1),
+			topology:   configv1.HighlyAvailableTopologyMode,
+			live:       livePDB(1),
+		},
+		{
+			name:       "highly available",
+			deployment: replicas(2),
+			topology:   configv1.HighlyAvailableTopologyMode,
+			expectPDB:  true,
+		},
+		{
+			name:       "highly available updates budget",
+			deployment: replicas(2),
+			topology:   configv1.HighlyAvailableTopologyMode,
+			live:       livePDB(2),
+			expectPDB:  true,
+		},
+		{
+			name:       "single replica topology",
+			deployment: replicas(2),
+			topology:   configv1.SingleReplicaTopologyMode,
+			live:       livePDB(1),
+		},
+		{
+			name:           "no infrastructure",
+			deployment:     replicas(2),
+			expectDegraded: true,
+		},
+	} {
+		kubeObjects := []runtime.Object{}
+		deploymentIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.deployment != nil {
+			deploymentIndexer.Add(test.deployment)
+		}
+		pdbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.live != nil {
+			pdbIndexer.Add(test.live)
+			kubeObjects = append(kubeObjects, test.live)
+		}
+		infraIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if len(test.topology) > 0 {
+			infraIndexer.Add(&configv1.Infrastructure{
+				ObjectMeta: metav1.ObjectMeta{Name: infraConfigName},
+				Status:     configv1.InfrastructureStatus{ControlPlaneTopology: test.topology},
+			})
+		}
+
+		kubeClient := fake.NewSimpleClientset(kubeObjects...)
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &webhookPDBController{
+			kubeClient:       kubeClient,
+			operatorClient:   operatorClient,
+			deploymentLister: appsv1listers.NewDeploymentLister(deploymentIndexer).Deployments(config.DefaultNamespace),
+			pdbLister:        policyv1listers.NewPodDisruptionBudgetLister(pdbIndexer).PodDisruptionBudgets(config.DefaultNamespace),
+			infraLister:      configv1listers.NewInfrastructureLister(infraIndexer),
+			required:         resourceread.ReadPodDisruptionBudgetV1OrDie(assets.MustAsset("webhook/pdb.yaml")),
+		}
+
+		recorder := events.NewInMemoryRecorder(pdbControllerName)
+		err := c.sync(context.TODO(), factory.NewSyncContext(pdbControllerName, recorder))
+		if (err != nil) != test.expectDegraded {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		degraded := v1helpers.IsOperatorConditionTrue(status.Conditions, WebhookPDBDegradedConditionType)
+		if degraded != test.expectDegraded {
+			t.Errorf("testcase %s: expected condition %s to be %v, got %v", test.name, WebhookPDBDegradedConditionType, test.expectDegraded, status.Conditions)
+		}
+		if test.expectDegraded {
+			continue
+		}
+
+		pdb, err := kubeClient.PolicyV1().PodDisruptionBudgets(config.DefaultNamespace).Get(context.TODO(), c.required.Name, metav1.GetOptions{})
+		switch {
+		case !test.expectPDB && !kerrors.IsNotFound(err):
+			t.Errorf("testcase %s: expected no PodDisruptionBudget, got %v, %v", test.name, pdb, err)
+		case test.expectPDB && err != nil:
+			t.Errorf("testcase %s: unexpected error %v", test.name, err)
+		case test.expectPDB:
+			if pdb.Spec.MinAvailable != nil || pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntValue() != 1 {
+				t.Errorf("testcase %s: expected maxUnavailable 1, got %#v", test.name, pdb.Spec)
+			}
+		}
+	}
+}
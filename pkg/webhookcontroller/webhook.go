@@ -0,0 +1,329 @@
+package webhookcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionregistrationv1informers "k8s.io/client-go/informers/admissionregistration/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	admissionregistrationv1listers "k8s.io/client-go/listers/admissionregistration/v1"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+const (
+	controllerName = "SharedResourceWebhookConfigurationController"
+
+	// webhookName is the name of the webhook Deployment and of the Service in front of it
+	webhookName = "shared-resource-csi-driver-webhook"
+	// hostedCAConfigMapName is the ConfigMap the service CA of the management cluster is injected into
+	hostedCAConfigMapName    = "shared-resource-csi-driver-webhook-ca"
+	hostedCAKey              = "service-ca.crt"
+	injectCABundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+
+	// WebhookConfigurationDegradedConditionType is reported on the ClusterCSIDriver when the
+	// ValidatingWebhookConfiguration cannot be applied.
+	WebhookConfigurationDegradedConditionType = "WebhookConfigurationDegraded"
+
+	resyncInterval = 10 * time.Minute
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// webhookConfigurationController applies the ValidatingWebhookConfiguration of the share validation webhook,
+// rendered from the embedded manifest and the webhook section of the operator configuration: failure policy,
+// timeout, excluded namespaces, namespace selector and whether share updates are validated.
+//
+// In a hosted control plane the webhook runs in the management cluster, which the API server of the guest cluster
+// reaches by URL. The ValidatingWebhookConfiguration then carries that URL and the service CA of the management
+// cluster, instead of a Service reference and a CA bundle injected in the guest cluster.
+//
+// With the Adaptive failure policy, Fail is used while the webhook Deployment has available replicas and its
+// Service has ready endpoints. Once the webhook has been unavailable for the configured window the policy falls
+// back to Ignore, so that a broken webhook does not block every pod mounting a share. When the operator restarts
+// the window starts over.
+type webhookConfigurationController struct {
+	kubeClient       kubernetes.Interface
+	operatorClient   v1helpers.OperatorClient
+	configMapLister  corev1listers.ConfigMapNamespaceLister
+	deploymentLister appsv1listers.DeploymentNamespaceLister
+	endpointsLister  corev1listers.EndpointsNamespaceLister
+	webhookLister    admissionregistrationv1listers.ValidatingWebhookConfigurationLister
+	required         *admissionregistrationv1.ValidatingWebhookConfiguration
+	resourceCache    resourceapply.ResourceCache
+
+	// hostedControlPlaneNamespace is where the webhook runs in the management cluster, empty when not hosted
+	hostedControlPlaneNamespace string
+	caConfigMapLister           corev1listers.ConfigMapNamespaceLister
+
+	// unavailableSince is when the webhook was first seen unavailable while the Fail policy was in effect
+	unavailableSince time.Time
+}
+
+// NewWebhookConfigurationController manages the ValidatingWebhookConfiguration. The webhook Deployment, its
+// Endpoints and, in a hosted control plane, the service CA ConfigMap are watched through controlPlaneInformers,
+// which are for hostedControlPlaneNamespace of the management cluster when it is set.
+func NewWebhookConfigurationController(kubeClient kubernetes.Interface,
+	operatorClient v1helpers.OperatorClient,
+	configMapInformer corev1informers.ConfigMapInformer,
+	controlPlaneInformers v1helpers.KubeInformersForNamespaces,
+	hostedControlPlaneNamespace string,
+	webhookInformer admissionregistrationv1informers.ValidatingWebhookConfigurationInformer,
+	recorder events.Recorder) factory.Controller {
+
+	namespace := config.DefaultNamespace
+	if len(hostedControlPlaneNamespace) > 0 {
+		namespace = hostedControlPlaneNamespace
+	}
+	deploymentInformer := controlPlaneInformers.InformersFor(namespace).Apps().V1().Deployments()
+	endpointsInformer := controlPlaneInformers.InformersFor(namespace).Core().V1().Endpoints()
+	caConfigMapInformer := controlPlaneInformers.InformersFor(namespace).Core().V1().ConfigMaps()
+
+	c := &webhookConfigurationController{
+		kubeClient:                  kubeClient,
+		operatorClient:              operatorClient,
+		configMapLister:             configMapInformer.Lister().ConfigMaps(config.DefaultNamespace),
+		deploymentLister:            deploymentInformer.Lister().Deployments(namespace),
+		endpointsLister:             endpointsInformer.Lister().Endpoints(namespace),
+		webhookLister:               webhookInformer.Lister(),
+		required:                    resourceread.ReadValidatingWebhookConfigurationV1OrDie(assets.MustAsset("webhook/validating_webhook_configuration.yaml")),
+		resourceCache:               resourceapply.NewResourceCache(),
+		hostedControlPlaneNamespace: hostedControlPlaneNamespace,
+		caConfigMapLister:           caConfigMapInformer.Lister().ConfigMaps(namespace),
+	}
+	return factory.New().WithFilteredEventsInformers(
+		factory.NamesFilter(config.OperatorConfigMapName),
+		configMapInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(hostedCAConfigMapName),
+		caConfigMapInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(webhookName),
+		deploymentInformer.Informer(),
+		endpointsInformer.Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(c.required.Name),
+		webhookInformer.Informer(),
+	).WithSync(
+		c.sync,
+	).ResyncEvery(
+		resyncInterval,
+	).ToController(
+		controllerName,
+		recorder.WithComponentSuffix("shared-resource-webhook-configuration-controller"),
+	)
+}
+
+func (c *webhookConfigurationController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	opConfig, err := config.GetOperatorConfig(c.configMapLister)
+	if err != nil {
+		return c.updateCondition(ctx, "InvalidConfiguration", err)
+	}
+
+	current, err := c.currentFailurePolicy()
+	if err != nil {
+		return c.updateCondition(ctx, "ApplyFailed", err)
+	}
+	policy := admissionregistrationv1.Ignore
+	switch opConfig.GetWebhookFailurePolicy() {
+	case config.WebhookFailurePolicyFail:
+		policy = admissionregistrationv1.Fail
+	case config.WebhookFailurePolicyAdaptive:
+		policy = c.adaptiveFailurePolicy(syncContext, current, opConfig.GetWebhookUnavailableWindow())
+	}
+
+	required, err := c.render(opConfig, policy)
+	if err != nil {
+		if updateErr := c.updateCondition(ctx, "RenderFailed", err); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	_, modified, err := resourceapply.ApplyValidatingWebhookConfigurationImproved(ctx, c.kubeClient.AdmissionregistrationV1(), syncContext.Recorder(), required, c.resourceCache)
+	if err != nil {
+		err = fmt.Errorf("error applying ValidatingWebhookConfiguration %q: %w", required.Name, err)
+		if updateErr := c.updateCondition(ctx, "ApplyFailed", err); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	if modified {
+		klog.Infof("ValidatingWebhookConfiguration %q was created or updated with failure policy %s", required.Name, policy)
+	}
+	if len(current) > 0 && current != policy {
+		if policy == admissionregistrationv1.Fail {
+			syncContext.Recorder().Eventf("WebhookFailurePolicyChanged", "Changed the webhook failure policy from %s to %s", current, policy)
+		} else {
+			syncContext.Recorder().Warningf("WebhookFailurePolicyChanged", "Changed the webhook failure policy from %s to %s", current, policy)
+		}
+	}
+	return c.updateCondition(ctx, "", nil)
+}
+
+// currentFailurePolicy returns the failure policy of the live ValidatingWebhookConfiguration, empty when it does
+// not exist yet.
+func (c *webhookConfigurationController) currentFailurePolicy() (admissionregistrationv1.FailurePolicyType, error) {
+	existing, err := c.webhookLister.Get(c.required.Name)
+	if kerrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unexpected error determining if %q exists: %s", c.required.Name, err)
+	}
+	for _, webhook := range existing.Webhooks {
+		if webhook.FailurePolicy != nil && *webhook.FailurePolicy == admissionregistrationv1.Fail {
+			return admissionregistrationv1.Fail, nil
+		}
+	}
+	return admissionregistrationv1.Ignore, nil
+}
+
+// adaptiveFailurePolicy returns Fail while the webhook is available. Once it is not, Fail is kept for window
+// after the webhook was first seen unavailable, and Ignore is returned from then on until it is available again.
+func (c *webhookConfigurationController) adaptiveFailurePolicy(syncContext factory.SyncContext, current admissionregistrationv1.FailurePolicyType, window time.Duration) admissionregistrationv1.FailurePolicyType {
+	unavailable := c.unavailableReason()
+	if len(unavailable) == 0 {
+		c.unavailableSince = time.Time{}
+		return admissionregistrationv1.Fail
+	}
+	if current != admissionregistrationv1.Fail {
+		klog.V(4).Infof("Keeping the webhook failure policy at Ignore: %s", unavailable)
+		return admissionregistrationv1.Ignore
+	}
+
+	if c.unavailableSince.IsZero() {
+		c.unavailableSince = now()
+	}
+	if remaining := c.unavailableSince.Add(window).Sub(now()); remaining > 0 {
+		klog.V(2).Infof("Webhook unavailable since %s: %s, falling back to the Ignore failure policy in %s", c.unavailableSince, unavailable, remaining)
+		syncContext.Queue().AddAfter(syncContext.QueueKey(), remaining)
+		return admissionregistrationv1.Fail
+	}
+	syncContext.Recorder().Warningf("WebhookUnavailable", "Webhook unavailable since %s: %s", c.unavailableSince.UTC().Format(time.RFC3339), unavailable)
+	return admissionregistrationv1.Ignore
+}
+
+// unavailableReason explains why the webhook cannot serve requests, and is empty when it can.
+func (c *webhookConfigurationController) unavailableReason() string {
+	deployment, err := c.deploymentLister.Get(webhookName)
+	if err != nil {
+		return fmt.Sprintf("unable to get Deployment %s: %s", webhookName, err)
+	}
+	if deployment.Status.AvailableReplicas == 0 {
+		return fmt.Sprintf("Deployment %s has no available replicas", webhookName)
+	}
+	endpoints, err := c.endpointsLister.Get(webhookName)
+	if err != nil {
+		return fmt.Sprintf("unable to get Endpoints %s: %s", webhookName, err)
+	}
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Service %s has no ready endpoints", webhookName)
+}
+
+// render returns the embedded ValidatingWebhookConfiguration with policy and the webhook section of opConfig
+// applied to every webhook.
+func (c *webhookConfigurationController) render(opConfig *config.OperatorConfig, policy admissionregistrationv1.FailurePolicyType) (*admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	required := c.required.DeepCopy()
+	var caBundle []byte
+	if len(c.hostedControlPlaneNamespace) > 0 {
+		cm, err := c.caConfigMapLister.Get(hostedCAConfigMapName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the service CA of the management cluster from ConfigMap %s/%s: %s", c.hostedControlPlaneNamespace, hostedCAConfigMapName, err)
+		}
+		if len(cm.Data[hostedCAKey]) == 0 {
+			return nil, fmt.Errorf("ConfigMap %s/%s has no %s yet", c.hostedControlPlaneNamespace, hostedCAConfigMapName, hostedCAKey)
+		}
+		caBundle = []byte(cm.Data[hostedCAKey])
+		delete(required.Annotations, injectCABundleAnnotation)
+	}
+
+	timeoutSeconds := opConfig.GetWebhookTimeoutSeconds()
+	for i := range required.Webhooks {
+		webhook := &required.Webhooks[i]
+		webhook.FailurePolicy = &policy
+		webhook.TimeoutSeconds = &timeoutSeconds
+		if service := webhook.ClientConfig.Service; service != nil && len(c.hostedControlPlaneNamespace) > 0 {
+			url := fmt.Sprintf("https://%s.%s.svc:%d%s", service.Name, c.hostedControlPlaneNamespace, *service.Port, *service.Path)
+			webhook.ClientConfig = admissionregistrationv1.WebhookClientConfig{URL: &url, CABundle: caBundle}
+		}
+		if opConfig.Webhook == nil {
+			continue
+		}
+
+		if webhook.NamespaceSelector == nil {
+			webhook.NamespaceSelector = &metav1.LabelSelector{}
+		}
+		if len(opConfig.Webhook.ExcludedNamespaces) > 0 {
+			webhook.NamespaceSelector.MatchExpressions = append(webhook.NamespaceSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      corev1.LabelMetadataName,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   opConfig.Webhook.ExcludedNamespaces,
+			})
+		}
+		if selector := opConfig.Webhook.NamespaceSelector; selector != nil {
+			for key, value := range selector.MatchLabels {
+				if webhook.NamespaceSelector.MatchLabels == nil {
+					webhook.NamespaceSelector.MatchLabels = map[string]string{}
+				}
+				webhook.NamespaceSelector.MatchLabels[key] = value
+			}
+			webhook.NamespaceSelector.MatchExpressions = append(webhook.NamespaceSelector.MatchExpressions, selector.MatchExpressions...)
+		}
+
+		if opConfig.Webhook.ValidateShareUpdates {
+			for j := range webhook.Rules {
+				if isShareRule(webhook.Rules[j]) {
+					webhook.Rules[j].Operations = []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}
+				}
+			}
+		}
+	}
+	return required, nil
+}
+
+// isShareRule is true for the rule matching SharedSecrets and SharedConfigMaps.
+func isShareRule(rule admissionregistrationv1.RuleWithOperations) bool {
+	for _, group := range rule.APIGroups {
+		if group == sharev1alpha1.GroupName {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *webhookConfigurationController) updateCondition(ctx context.Context, reason string, err error) error {
+	condition := operatorv1.OperatorCondition{
+		Type:   WebhookConfigurationDegradedConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	if err != nil {
+		condition.Status = operatorv1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = err.Error()
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(condition))
+	return updateErr
+}
@@ -0,0 +1,302 @@
+package webhookcontroller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	admissionregistrationv1listers "k8s.io/client-go/listers/admissionregistration/v1"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/csi-driver-shared-resource-operator/assets"
+	"github.com/openshift/csi-driver-shared-resource-operator/pkg/config"
+)
+
+func webhookDeployment(availableReplicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: webhookName, Namespace: config.DefaultNamespace},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: availableReplicas},
+	}
+}
+
+func webhookEndpoints(addresses ...string) *corev1.Endpoints {
+	endpoints := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: webhookName, Namespace: config.DefaultNamespace}}
+	subset := corev1.EndpointSubset{}
+	for _, ip := range addresses {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: ip})
+	}
+	endpoints.Subsets = []corev1.EndpointSubset{subset}
+	return endpoints
+}
+
+func liveWebhookConfiguration(policy admissionregistrationv1.FailurePolicyType) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	required := resourceread.ReadValidatingWebhookConfigurationV1OrDie(assets.MustAsset("webhook/validating_webhook_configuration.yaml"))
+	for i := range required.Webhooks {
+		required.Webhooks[i].FailurePolicy = &policy
+	}
+	return required
+}
+
+func TestSync(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		operatorConfig   string
+		live             *admissionregistrationv1.ValidatingWebhookConfiguration
+		deployment       *appsv1.Deployment
+		endpoints        *corev1.Endpoints
+		unavailableSince time.Duration
+		expectPolicy     admissionregistrationv1.FailurePolicyType
+		expectTimeout    int32
+		expectEvents     []string
+		expectDegraded   bool
+	}{
+		{
+			name:          "defaults",
+			expectPolicy:  admissionregistrationv1.Ignore,
+			expectTimeout: 10,
+		},
+		{
+			name:           "configured policy and timeout",
+			operatorConfig: "webhook:\n  failurePolicy: Fail\n  timeoutSeconds: 5\n",
+			live:           liveWebhookConfiguration(admissionregistrationv1.Ignore),
+			expectPolicy:   admissionregistrationv1.Fail,
+			expectTimeout:  5,
+			expectEvents:   []string{"WebhookFailurePolicyChanged"},
+		},
+		{
+			name:           "adaptive policy fails once the webhook is available",
+			operatorConfig: "webhook:\n  failurePolicy: Adaptive\n",
+			live:           liveWebhookConfiguration(admissionregistrationv1.Ignore),
+			deployment:     webhookDeployment(1),
+			endpoints:      webhookEndpoints("10.0.0.1"),
+			expectPolicy:   admissionregistrationv1.Fail,
+			expectTimeout:  10,
+			expectEvents:   []string{"WebhookFailurePolicyChanged"},
+		},
+		{
+			name:           "adaptive policy keeps failing within the unavailable window",
+			operatorConfig: "webhook:\n  failurePolicy: Adaptive\n",
+			live:           liveWebhookConfiguration(admissionregistrationv1.Fail),
+			deployment:     webhookDeployment(1),
+			endpoints:      webhookEndpoints(),
+			expectPolicy:   admissionregistrationv1.Fail,
+			expectTimeout:  10,
+		},
+		{
+			name:             "adaptive policy falls back to ignore after the unavailable window",
+			operatorConfig:   "webhook:\n  failurePolicy: Adaptive\n  unavailableWindow: 2m\n",
+			live:             liveWebhookConfiguration(admissionregistrationv1.Fail),
+			deployment:       webhookDeployment(0),
+			unavailableSince: 3 * time.Minute,
+			expectPolicy:     admissionregistrationv1.Ignore,
+			expectTimeout:    10,
+			expectEvents:     []string{"WebhookUnavailable", "WebhookFailurePolicyChanged"},
+		},
+		{
+			name:           "adaptive policy keeps ignoring while the webhook is unavailable",
+			operatorConfig: "webhook:\n  failurePolicy: Adaptive\n",
+			live:           liveWebhookConfiguration(admissionregistrationv1.Ignore),
+			expectPolicy:   admissionregistrationv1.Ignore,
+			expectTimeout:  10,
+		},
+		{
+			name:           "invalid configuration is reported",
+			operatorConfig: "webhook:\n  timeoutSeconds: 60\n",
+			expectDegraded: true,
+		},
+	} {
+		kubeObjects := []runtime.Object{}
+		webhookIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.live != nil {
+			webhookIndexer.Add(test.live)
+			kubeObjects = append(kubeObjects, test.live)
+		}
+		deploymentIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.deployment != nil {
+			deploymentIndexer.Add(test.deployment)
+		}
+		endpointsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if test.endpoints != nil {
+			endpointsIndexer.Add(test.endpoints)
+		}
+		cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if len(test.operatorConfig) > 0 {
+			cmIndexer.Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: config.OperatorConfigMapName, Namespace: config.DefaultNamespace},
+				Data:       map[string]string{config.ConfigKey: test.operatorConfig},
+			})
+		}
+
+		kubeClient := fake.NewSimpleClientset(kubeObjects...)
+		operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+		c := &webhookConfigurationController{
+			kubeClient:       kubeClient,
+			operatorClient:   operatorClient,
+			configMapLister:  corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps(config.DefaultNamespace),
+			deploymentLister: appsv1listers.NewDeploymentLister(deploymentIndexer).Deployments(config.DefaultNamespace),
+			endpointsLister:  corev1listers.NewEndpointsLister(endpointsIndexer).Endpoints(config.DefaultNamespace),
+			webhookLister:    admissionregistrationv1listers.NewValidatingWebhookConfigurationLister(webhookIndexer),
+			required:         resourceread.ReadValidatingWebhookConfigurationV1OrDie(assets.MustAsset("webhook/validating_webhook_configuration.yaml")),
+			resourceCache:    resourceapply.NewResourceCache(),
+		}
+		if test.unavailableSince > 0 {
+			c.unavailableSince = now().Add(-test.unavailableSince)
+		}
+
+		recorder := events.NewInMemoryRecorder(controllerName)
+		if err := c.sync(context.TODO(), factory.NewSyncContext(controllerName, recorder)); err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+
+		_, status, _, _ := operatorClient.GetOperatorState()
+		degraded := v1helpers.IsOperatorConditionTrue(status.Conditions, WebhookConfigurationDegradedConditionType)
+		if degraded != test.expectDegraded {
+			t.Errorf("testcase %s: expected condition %s to be %v, got %v", test.name, WebhookConfigurationDegradedConditionType, test.expectDegraded, status.Conditions)
+		}
+		if test.expectDegraded {
+			continue
+		}
+
+		reasons := []string{}
+		for _, event := range recorder.Events() {
+			if strings.HasPrefix(event.Reason, "Webhook") {
+				reasons = append(reasons, event.Reason)
+			}
+		}
+		if strings.Join(reasons, ",") != strings.Join(test.expectEvents, ",") {
+			t.Errorf("testcase %s: expected events %v, got %v", test.name, test.expectEvents, reasons)
+		}
+
+		applied, err := kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.TODO(), c.required.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		for _, webhook := range applied.Webhooks {
+			if *webhook.FailurePolicy != test.expectPolicy || *webhook.TimeoutSeconds != test.expectTimeout {
+				t.Errorf("testcase %s: expected failure policy %s and timeout %d, got %s and %d", test.name, test.expectPolicy, test.expectTimeout, *webhook.FailurePolicy, *webhook.TimeoutSeconds)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		operatorConfig    string
+		expectExpressions []string
+		expectLabels      map[string]string
+		expectOperations  []admissionregistrationv1.OperationType
+	}{
+		{
+			name:              "embedded manifest",
+			expectExpressions: []string{"runlevel", "openshift.io/run-level", "csi.sharedresource.openshift.io/skip-validation"},
+			expectOperations:  []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+		},
+		{
+			name: "excluded namespaces, selector and share updates",
+			operatorConfig: `webhook:
+  excludedNamespaces: [ci-runners, sandbox]
+  namespaceSelector:
+    matchLabels:
+      validate-shares: "true"
+    matchExpressions:
+    - key: tenant
+      operator: DoesNotExist
+  validateShareUpdates: true
+`,
+			expectExpressions: []string{"runlevel", "openshift.io/run-level", "csi.sharedresource.openshift.io/skip-validation", corev1.LabelMetadataName, "tenant"},
+			expectLabels:      map[string]string{"validate-shares": "true"},
+			expectOperations:  []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+		},
+	} {
+		opConfig := &config.OperatorConfig{}
+		if len(test.operatorConfig) > 0 {
+			var err error
+			if opConfig, err = config.ParseOperatorConfig([]byte(test.operatorConfig)); err != nil {
+				t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+			}
+		}
+		c := &webhookConfigurationController{
+			required: resourceread.ReadValidatingWebhookConfigurationV1OrDie(assets.MustAsset("webhook/validating_webhook_configuration.yaml")),
+		}
+
+		rendered, err := c.render(opConfig, admissionregistrationv1.Ignore)
+		if err != nil {
+			t.Fatalf("testcase %s: unexpected error %v", test.name, err)
+		}
+		for _, webhook := range rendered.Webhooks {
+			keys := []string{}
+			for _, requirement := range webhook.NamespaceSelector.MatchExpressions {
+				keys = append(keys, requirement.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(test.expectExpressions, ",") {
+				t.Errorf("testcase %s: expected namespace selector keys %v, got %v", test.name, test.expectExpressions, keys)
+			}
+			if len(webhook.NamespaceSelector.MatchLabels) != len(test.expectLabels) {
+				t.Errorf("testcase %s: expected namespace selector labels %v, got %v", test.name, test.expectLabels, webhook.NamespaceSelector.MatchLabels)
+			}
+			for _, rule := range webhook.Rules {
+				operations := test.expectOperations
+				if !isShareRule(rule) {
+					operations = []admissionregistrationv1.OperationType{admissionregistrationv1.Create}
+				}
+				if len(rule.Operations) != len(operations) {
+					t.Errorf("testcase %s: expected operations %v for %v, got %v", test.name, operations, rule.Resources, rule.Operations)
+				}
+			}
+		}
+		if len(c.required.Webhooks[0].NamespaceSelector.MatchExpressions) != 3 {
+			t.Errorf("testcase %s: rendering modified the embedded manifest", test.name)
+		}
+	}
+}
+
+func TestRenderHostedControlPlane(t *testing.T) {
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &webhookConfigurationController{
+		required:                    resourceread.ReadValidatingWebhookConfigurationV1OrDie(assets.MustAsset("webhook/validating_webhook_configuration.yaml")),
+		hostedControlPlaneNamespace: "clusters-guest",
+		caConfigMapLister:           corev1listers.NewConfigMapLister(cmIndexer).ConfigMaps("clusters-guest"),
+	}
+
+	if _, err := c.render(&config.OperatorConfig{}, admissionregistrationv1.Ignore); err == nil {
+		t.Errorf("expected an error while the service CA is not injected")
+	}
+
+	cmIndexer.Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: hostedCAConfigMapName, Namespace: "clusters-guest"},
+		Data:       map[string]string{hostedCAKey: "-----BEGIN CERTIFICATE-----"},
+	})
+	rendered, err := c.render(&config.OperatorConfig{}, admissionregistrationv1.Ignore)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := rendered.Annotations[injectCABundleAnnotation]; ok {
+		t.Errorf("expected no %s annotation, got %v", injectCABundleAnnotation, rendered.Annotations)
+	}
+	for _, webhook := range rendered.Webhooks {
+		clientConfig := webhook.ClientConfig
+		if clientConfig.Service != nil || clientConfig.URL == nil || *clientConfig.URL != "https://shared-resource-csi-driver-webhook.clusters-guest.svc:443/resource-validation" {
+			t.Errorf("unexpected client config %v", clientConfig)
+		}
+		if string(clientConfig.CABundle) != "-----BEGIN CERTIFICATE-----" {
+			t.Errorf("unexpected CA bundle %q", clientConfig.CABundle)
+		}
+	}
+}